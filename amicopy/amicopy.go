@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -17,67 +19,364 @@ import (
 
 // AmiCopy defines the interface to copy images
 type AmiCopy interface {
+	Complete(ctx context.Context, ui *packer.Ui) error
 	Copy(ctx context.Context, ui *packer.Ui) error
+	Created() bool
+	Deregister(ctx context.Context) error
+	Finalize(ctx context.Context, ui *packer.Ui) error
 	Input() *ec2.CopyImageInput
+	LineageKey() string
+	ManageLineage(ctx context.Context, ui *packer.Ui, runImageIDs []string, copies int) error
+	Manifest() *AmiManifest
 	Output() *ec2.CopyImageOutput
+	Resume(ctx context.Context, imageID string) (bool, error)
+	Submit(ctx context.Context, ui *packer.Ui) error
 	Tag(ctx context.Context) error
 	TargetAccountID() string
+	TargetRegion() string
 }
 
+// Defaults for waiting on copies to become available.
+const (
+	DefaultAvailableTimeout = 30 * time.Minute
+	DefaultPollInterval     = time.Minute
+)
+
 // AmiCopyImpl holds data and methods related to copying an image.
 type AmiCopyImpl struct {
-	targetAccountID string
-	EC2             *ec2.Client
-	input           *ec2.CopyImageInput
-	output          *ec2.CopyImageOutput
-	SourceImage     *ec2types.Image
-	EnsureAvailable bool
-	KeepArtifact    bool
-	TagsOnly        bool
+	targetAccountID  string
+	targetRegion     string
+	EC2              *ec2.Client
+	input            *ec2.CopyImageInput
+	output           *ec2.CopyImageOutput
+	image            *ec2types.Image
+	created          bool
+	status           string
+	err              error
+	startTime        time.Time
+	endTime          time.Time
+	SourceImage      *ec2types.Image
+	BuildName        string
+	Tags             []ec2types.Tag
+	SnapshotTags     []ec2types.Tag
+	TagFilter        *TagFilter
+	Provenance       *Provenance
+	SSMParameter     *SSMParameter
+	LaunchTemplates  *LaunchTemplates
+	Retention        *Retention
+	Deprecation      *Deprecation
+	DeprecateAt      time.Time
+	EnsureAvailable  bool
+	AvailableTimeout time.Duration
+	PollInterval     time.Duration
+	Poller           *Poller
+	SkipExisting     bool
+	TagsOnly         bool
 }
 
-// AmiManifest holds the data about the resulting copied image
-type AmiManifest struct {
-	AccountID string `json:"account_id"`
-	Region    string `json:"region"`
-	ImageID   string `json:"image_id"`
+// Copy will perform an EC2 copy based on the `Input` field.
+// The copy is tagged on creation, and Tag is only called to tag existing
+// images.
+//
+// If `SkipExisting` is set and a previous copy of the source image is found in
+// the target then it is reused rather than copied again.
+//
+// Copy is Submit followed by Complete, which may instead be called separately
+// so that copies are not held up waiting on each other. Finalize is left to be
+// called once every copy in the run has succeeded.
+func (ac *AmiCopyImpl) Copy(ctx context.Context, ui *packer.Ui) error {
+	if err := ac.Submit(ctx, ui); err != nil {
+		return err
+	}
+	return ac.Complete(ctx, ui)
 }
 
-// Copy will perform an EC2 copy based on the `Input` field.
-// It will also call Tag to copy the source tags, if any.
-func (ac *AmiCopyImpl) Copy(ctx context.Context, ui *packer.Ui) (err error) {
+// Submit starts the copy, or finds an existing one, and tags it. The copy may
+// still be pending once it returns. Submit may be retried after it fails.
+func (ac *AmiCopyImpl) Submit(ctx context.Context, ui *packer.Ui) (err error) {
+	ac.status = ManifestStatusCopied
+	ac.startTime = time.Now().UTC()
+	ac.endTime, ac.err, ac.created = time.Time{}, nil, false
+	defer func() {
+		if err != nil {
+			ac.fail(err)
+		}
+	}()
+
 	if !ac.TagsOnly {
-		if ac.output, err = ac.EC2.CopyImage(ctx, ac.input); err != nil {
+		var existing *ec2types.Image
+		if ac.SkipExisting {
+			if existing, err = ac.locateExisting(ctx); err != nil {
+				return err
+			}
+		}
+
+		if existing != nil {
+			(*ui).Say(fmt.Sprintf("Found existing copy %s of %s in %s on account %s, skipping copy",
+				*existing.ImageId, *ac.input.SourceImageId, ac.targetRegion, ac.targetAccountID))
+			ac.status = ManifestStatusExisting
+			ac.output = &ec2.CopyImageOutput{ImageId: existing.ImageId}
+			ac.image = existing
+		} else if ac.output, err = ac.EC2.CopyImage(ctx, ac.taggedInput()); err != nil {
 			return err
+		} else {
+			ac.created = true
 		}
 	} else {
 		(*ui).Say(fmt.Sprintf("Only copying tags in %s as tags_only=true", ac.targetAccountID))
 		ac.output = &ec2.CopyImageOutput{ImageId: ac.input.SourceImageId}
 	}
 
-	if err = ac.Tag(ctx); err != nil {
-		return err
+	// New copies are tagged on creation, only tags only and existing copies
+	// need tagging here.
+	if ac.status == ManifestStatusExisting || ac.TagsOnly {
+		if err = ac.Tag(ctx); err != nil {
+			return err
+		}
+	}
+	if ac.status == ManifestStatusExisting {
+		if err = ac.tagSnapshots(ctx); err != nil {
+			return fmt.Errorf("Unable to tag snapshots of image %s on account %s: %s",
+				*ac.output.ImageId, ac.targetAccountID, err)
+		}
 	}
 
+	return nil
+}
+
+// Complete waits for a submitted copy to become available, if
+// `EnsureAvailable` is set.
+func (ac *AmiCopyImpl) Complete(ctx context.Context, ui *packer.Ui) (err error) {
+	defer func() {
+		ac.endTime = time.Now().UTC()
+		if err != nil {
+			ac.fail(err)
+		}
+	}()
+
 	if ac.EnsureAvailable {
-		(*ui).Say("Going to wait for image to be in available state")
-		for i := 1; i <= 30; i++ {
-			image, err := LocateSingleAMI(ctx, aws.ToString(ac.output.ImageId), ac.EC2)
-			if err != nil && image == nil {
-				return err
-			}
-			if image.State == ec2types.ImageStateAvailable {
-				return nil
-			}
-			(*ui).Say(fmt.Sprintf("Waiting one minute (%d/30) for AMI to become available, current state: %s for image %s on account %s", i, image.State, *image.ImageId, ac.targetAccountID))
-			time.Sleep(time.Duration(1) * time.Minute)
+		if err = ac.waitAvailable(ctx, ui); err != nil {
+			return err
+		}
+	} else if image, err := LocateSingleAMI(ctx, aws.ToString(ac.output.ImageId), ac.EC2); err == nil {
+		// Record what is known of the image so far for the manifest.
+		ac.image = image
+	}
+
+	if !ac.DeprecateAt.IsZero() && !ac.TagsOnly {
+		if err = DeprecateImage(ctx, ac.EC2, aws.ToString(ac.output.ImageId), ac.DeprecateAt); err != nil {
+			return fmt.Errorf("Unable to deprecate image %s on account %s: %s",
+				*ac.output.ImageId, ac.targetAccountID, err)
+		}
+	}
+
+	return nil
+}
+
+// Finalize publishes the copy once every copy in the run has succeeded, so
+// that nothing is published for a copy that may yet be rolled back.
+func (ac *AmiCopyImpl) Finalize(ctx context.Context, ui *packer.Ui) (err error) {
+	defer func() {
+		if err != nil {
+			ac.fail(err)
+		}
+	}()
+
+	if ac.SSMParameter != nil {
+		version, err := ac.SSMParameter.Publish(ctx, aws.ToString(ac.output.ImageId))
+		if err != nil {
+			return fmt.Errorf("Unable to publish image %s to SSM parameter %s on account %s: %s",
+				*ac.output.ImageId, ac.SSMParameter.Name, ac.targetAccountID, err)
+		}
+		(*ui).Say(fmt.Sprintf("Published image %s to SSM parameter %s (version %d) on account %s",
+			*ac.output.ImageId, ac.SSMParameter.Name, version, ac.targetAccountID))
+	}
+
+	if ac.LaunchTemplates != nil {
+		updated, err := ac.LaunchTemplates.Update(ctx, ac.EC2, aws.ToString(ac.output.ImageId))
+		if err != nil {
+			return fmt.Errorf("Unable to update launch templates to image %s on account %s: %s",
+				*ac.output.ImageId, ac.targetAccountID, err)
+		}
+		for _, template := range updated {
+			(*ui).Say(fmt.Sprintf("Updated launch template %s to image %s on account %s",
+				template, *ac.output.ImageId, ac.targetAccountID))
 		}
-		return fmt.Errorf("Timed out waiting for image %s to copy to account %s", *ac.output.ImageId, ac.targetAccountID)
 	}
 
 	return nil
 }
 
+// LineageKey identifies the account, region and lineage whose older images the
+// copy prunes or deprecates, being empty if it does neither.
+func (ac *AmiCopyImpl) LineageKey() string {
+	var lineage *Lineage
+	switch {
+	case ac.TagsOnly:
+		return ""
+	case ac.Retention != nil:
+		lineage = &ac.Retention.Lineage
+	case ac.Deprecation != nil:
+		lineage = &ac.Deprecation.Lineage
+	default:
+		return ""
+	}
+	key := lineage.Key(ac.tags())
+	if key == "" {
+		return ""
+	}
+	return ac.targetAccountID + "/" + ac.targetRegion + "/" + key
+}
+
+// ManageLineage prunes or deprecates the older images in the lineage of the
+// copy once every copy in the run has succeeded. It is run once for the
+// `copies` of the run in the same lineage, and the images of the run, both
+// sources and copies, are never pruned or deprecated.
+func (ac *AmiCopyImpl) ManageLineage(ctx context.Context, ui *packer.Ui, runImageIDs []string, copies int) (
+	err error) {
+
+	defer func() {
+		if err != nil {
+			ac.fail(err)
+		}
+	}()
+
+	if ac.Retention != nil && !ac.TagsOnly {
+		pruned, err := ac.Retention.Prune(ctx, ac.EC2, ac.tags(), copies, runImageIDs...)
+		for _, imageID := range pruned {
+			(*ui).Say(fmt.Sprintf("Pruned older image %s in %s on account %s",
+				imageID, ac.targetRegion, ac.targetAccountID))
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to prune older images in %s on account %s: %s",
+				ac.targetRegion, ac.targetAccountID, err)
+		}
+	}
+
+	if ac.Deprecation != nil && !ac.TagsOnly {
+		deprecated, disabled, err := ac.Deprecation.Deprecate(ctx, ac.EC2, ac.tags(), runImageIDs...)
+		for _, imageID := range deprecated {
+			(*ui).Say(fmt.Sprintf("Deprecated older image %s in %s on account %s",
+				imageID, ac.targetRegion, ac.targetAccountID))
+		}
+		for _, imageID := range disabled {
+			(*ui).Say(fmt.Sprintf("Disabled older image %s in %s on account %s",
+				imageID, ac.targetRegion, ac.targetAccountID))
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to deprecate older images in %s on account %s: %s",
+				ac.targetRegion, ac.targetAccountID, err)
+		}
+	}
+
+	return nil
+}
+
+// fail marks the copy as failed with the error.
+func (ac *AmiCopyImpl) fail(err error) {
+	if ac.endTime.IsZero() {
+		ac.endTime = time.Now().UTC()
+	}
+	ac.status = ManifestStatusFailed
+	ac.err = err
+}
+
+// waitAvailable waits for the copied image to become available through
+// `Poller`, for up to `AvailableTimeout`. Copies without a shared Poller poll
+// on their own every `PollInterval`.
+func (ac *AmiCopyImpl) waitAvailable(ctx context.Context, ui *packer.Ui) error {
+	timeout := ac.AvailableTimeout
+	if timeout <= 0 {
+		timeout = DefaultAvailableTimeout
+	}
+	poller := ac.Poller
+	if poller == nil {
+		poller = &Poller{
+			EC2:       ac.EC2,
+			AccountID: ac.targetAccountID,
+			Region:    ac.targetRegion,
+			Interval:  ac.PollInterval,
+			Ui:        *ui,
+		}
+		if poller.Interval <= 0 {
+			poller.Interval = DefaultPollInterval
+		}
+	}
+
+	(*ui).Say(fmt.Sprintf("Going to wait up to %s for image %s to be in available state on account %s",
+		timeout, *ac.output.ImageId, ac.targetAccountID))
+	image, err := poller.Wait(ctx, aws.ToString(ac.output.ImageId), timeout)
+	if image != nil {
+		ac.image = image
+	}
+	return err
+}
+
+// stateReason describes why the image is in its current state.
+func stateReason(image *ec2types.Image) string {
+	if image.StateReason == nil || image.StateReason.Message == nil {
+		return "no reason given"
+	}
+	if image.StateReason.Code != nil {
+		return fmt.Sprintf("%s (%s)", *image.StateReason.Message, *image.StateReason.Code)
+	}
+	return *image.StateReason.Message
+}
+
+// Created reports whether the image was created by this copy, rather than
+// being an existing or resumed copy, or the source image in tags only mode.
+func (ac *AmiCopyImpl) Created() bool {
+	return ac.created
+}
+
+// Deregister will remove the copied image and its snapshots from the target.
+// Nothing is removed unless the image was created by this copy, as existing
+// images may be in use, and for tags only copies the image is the source.
+func (ac *AmiCopyImpl) Deregister(ctx context.Context) error {
+	if !ac.created || ac.output == nil {
+		return nil
+	}
+
+	image, err := LocateSingleAMI(ctx, aws.ToString(ac.output.ImageId), ac.EC2)
+	if err != nil {
+		return err
+	}
+	if _, err = DeregisterImage(ctx, ac.EC2, image); err != nil {
+		return err
+	}
+	image.State = ec2types.ImageStateDeregistered
+	ac.image = image
+	ac.status = ManifestStatusDeregistered
+	return nil
+}
+
+// Resume will reuse the image from a previous copy if it still exists in the
+// target, reporting whether it was reused. The image may still be pending, so
+// a resumed copy must be completed like a submitted one.
+func (ac *AmiCopyImpl) Resume(ctx context.Context, imageID string) (bool, error) {
+	output, err := ac.EC2.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String("image-id"),
+				Values: []string{imageID},
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, image := range output.Images {
+		if image.State == ec2types.ImageStateAvailable ||
+			image.State == ec2types.ImageStatePending {
+			ac.status = ManifestStatusExisting
+			ac.output = &ec2.CopyImageOutput{ImageId: image.ImageId}
+			ac.image = &image
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (ac *AmiCopyImpl) Input() *ec2.CopyImageInput {
 	return ac.input
 }
@@ -86,6 +385,54 @@ func (ac *AmiCopyImpl) SetInput(input *ec2.CopyImageInput) {
 	ac.input = input
 }
 
+// Manifest returns the manifest entry describing the current state of the
+// copy.
+func (ac *AmiCopyImpl) Manifest() *AmiManifest {
+	manifest := &AmiManifest{
+		BuildName:     ac.BuildName,
+		AccountID:     ac.targetAccountID,
+		Region:        ac.targetRegion,
+		SourceImageID: aws.ToString(ac.input.SourceImageId),
+		SourceRegion:  aws.ToString(ac.input.SourceRegion),
+		Name:          aws.ToString(ac.input.Name),
+		Status:        ac.status,
+		TagsOnly:      ac.TagsOnly,
+	}
+	if !ac.TagsOnly {
+		manifest.Encrypted = aws.ToBool(ac.input.Encrypted)
+		manifest.KmsKeyID = aws.ToString(ac.input.KmsKeyId)
+	}
+	if ac.output != nil {
+		manifest.ImageID = aws.ToString(ac.output.ImageId)
+	}
+	if ac.image != nil {
+		manifest.State = string(ac.image.State)
+		manifest.SnapshotIDs = imageSnapshotIDs(ac.image)
+
+		// The image may be an existing copy, so record how it is actually
+		// encrypted rather than what was asked for.
+		if encrypted, kmsKeyIDs := imageEncryption(ac.image); encrypted != nil && !ac.TagsOnly {
+			manifest.Encrypted, manifest.KmsKeyID = *encrypted, ""
+			if len(kmsKeyIDs) > 0 {
+				manifest.KmsKeyID = kmsKeyIDs[0]
+			}
+		}
+	}
+	if manifest.Status == "" {
+		manifest.Status = ManifestStatusPending
+	}
+	if !ac.startTime.IsZero() {
+		manifest.StartTime = &ac.startTime
+	}
+	if !ac.endTime.IsZero() {
+		manifest.EndTime = &ac.endTime
+	}
+	if ac.err != nil {
+		manifest.Error = ac.err.Error()
+	}
+	return manifest
+}
+
 func (ac *AmiCopyImpl) Output() *ec2.CopyImageOutput {
 	return ac.output
 }
@@ -98,41 +445,258 @@ func (ac *AmiCopyImpl) SetTargetAccountID(id string) {
 	ac.targetAccountID = id
 }
 
-// Tag will copy tags from the source image to the target (if any).
+func (ac *AmiCopyImpl) TargetRegion() string {
+	return ac.targetRegion
+}
+
+func (ac *AmiCopyImpl) SetTargetRegion(region string) {
+	ac.targetRegion = region
+}
+
+// Tag will copy tags from the source image to the target (if any), filtered by
+// `TagFilter`, along with the additional `Tags`. It is used where the image
+// already exists, such as shared images in tags only mode, and retries until
+// the image is visible to the target. Additional tags take precedence over
+// source tags with the same key.
 func (ac *AmiCopyImpl) Tag(ctx context.Context) (err error) {
-	if len(ac.SourceImage.Tags) == 0 {
+	tags := ac.existingTags()
+	if len(tags) == 0 {
 		return nil
 	}
 
-	// Retry creating tags for about 2.5 minutes
+	// Retry for about 2.5 minutes while the image is not yet visible, which
+	// the AWS SDK retryer does not consider retryable.
 	return retry.Config{
 		Tries: 11,
 		ShouldRetry: func(err error) bool {
 			var ae smithy.APIError
-			if errors.As(err, &ae) {
-				return ae.ErrorCode() == "UnauthorizedOperation"
-			}
-			return false
+			return errors.As(err, &ae) && ae.ErrorCode() == "InvalidAMIID.NotFound"
 		},
 		RetryDelay: (&retry.Backoff{InitialBackoff: 200 * time.Millisecond, MaxBackoff: 30 * time.Second, Multiplier: 2}).Linear,
 	}.Run(ctx, func(ctx context.Context) error {
 		_, err := ac.EC2.CreateTags(ctx, &ec2.CreateTagsInput{
 			Resources: []string{aws.ToString(ac.output.ImageId)},
-			Tags:      ac.SourceImage.Tags,
+			Tags:      tags,
 		})
+		return err
+	})
+}
+
+// errSnapshotsPending is returned while the snapshots of an image are still
+// being created.
+var errSnapshotsPending = errors.New("snapshots are pending")
 
-		var ae smithy.APIError
-		if errors.As(err, &ae) {
-			if ae.ErrorCode() == "InvalidAMIID.NotFound" ||
-				ae.ErrorCode() == "InvalidSnapshot.NotFound" {
-				return nil
+// tagSnapshots tags the snapshots of the copy, found from its block device
+// mappings, retrying until they exist.
+func (ac *AmiCopyImpl) tagSnapshots(ctx context.Context) error {
+	tags := mergeTags(ac.existingTags(), ac.SnapshotTags)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	// Retry for about 2.5 minutes while the snapshots do not yet exist, as
+	// with Tag
+	return retry.Config{
+		Tries: 11,
+		ShouldRetry: func(err error) bool {
+			if errors.Is(err, errSnapshotsPending) {
+				return true
 			}
+			var ae smithy.APIError
+			return errors.As(err, &ae) && ae.ErrorCode() == "InvalidSnapshot.NotFound"
+		},
+		RetryDelay: (&retry.Backoff{InitialBackoff: 200 * time.Millisecond, MaxBackoff: 30 * time.Second, Multiplier: 2}).Linear,
+	}.Run(ctx, func(ctx context.Context) error {
+		image, err := LocateSingleAMI(ctx, aws.ToString(ac.output.ImageId), ac.EC2)
+		if err != nil {
+			return err
+		}
+		snapshotIDs := imageSnapshotIDs(image)
+		if len(snapshotIDs) == 0 || len(snapshotIDs) < len(imageEBSMappings(image)) {
+			return errSnapshotsPending
 		}
 
+		_, err = ac.EC2.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: snapshotIDs,
+			Tags:      tags,
+		})
 		return err
 	})
 }
 
+// snapshotTags returns the tags of the snapshots of the copy, being the tags
+// of the copy and the additional `SnapshotTags`.
+func (ac *AmiCopyImpl) snapshotTags() []ec2types.Tag {
+	return mergeTags(ac.tags(), ac.SnapshotTags)
+}
+
+// taggedInput returns the input to CopyImage with the tags of the copy. Where
+// the target owns the source image and its tags are copied unchanged, AWS is
+// asked to copy them with `CopyImageTags` and only the additional tags are
+// given.
+func (ac *AmiCopyImpl) taggedInput() *ec2.CopyImageInput {
+	input := *ac.input
+
+	imageTags := ac.tags()
+	if ac.copyImageTags() {
+		input.CopyImageTags = aws.Bool(true)
+		imageTags = ac.additionalTags()
+	}
+
+	input.TagSpecifications = nil
+	if len(imageTags) > 0 {
+		input.TagSpecifications = append(input.TagSpecifications, ec2types.TagSpecification{
+			ResourceType: ec2types.ResourceTypeImage,
+			Tags:         imageTags,
+		})
+	}
+	if snapshotTags := ac.snapshotTags(); len(snapshotTags) > 0 {
+		input.TagSpecifications = append(input.TagSpecifications, ec2types.TagSpecification{
+			ResourceType: ec2types.ResourceTypeSnapshot,
+			Tags:         snapshotTags,
+		})
+	}
+	return &input
+}
+
+// copyImageTags reports whether CopyImage can copy the source image tags
+// itself. It can only see the tags of images owned by the target, and the tags
+// must not be filtered or overridden.
+func (ac *AmiCopyImpl) copyImageTags() bool {
+	if ac.TagFilter != nil || aws.ToString(ac.SourceImage.OwnerId) != ac.targetAccountID {
+		return false
+	}
+	for _, tag := range ac.additionalTags() {
+		for _, sourceTag := range ac.SourceImage.Tags {
+			if aws.ToString(tag.Key) == aws.ToString(sourceTag.Key) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// tags returns the tags of the copy, being the filtered source image tags and
+// the additional tags.
+func (ac *AmiCopyImpl) tags() []ec2types.Tag {
+	return mergeTags(ac.TagFilter.Apply(ac.SourceImage.Tags), ac.additionalTags())
+}
+
+// existingTags returns the tags to apply to an image that already exists. It
+// keeps the provenance tags it has, as they record where it came from rather
+// than this run.
+func (ac *AmiCopyImpl) existingTags() []ec2types.Tag {
+	return mergeTags(ac.TagFilter.Apply(ac.SourceImage.Tags), ac.Tags)
+}
+
+// additionalTags returns the tags of the copy that are not from the source
+// image, being `Tags` and the provenance tags, if any.
+func (ac *AmiCopyImpl) additionalTags() []ec2types.Tag {
+	if ac.Provenance == nil {
+		return ac.Tags
+	}
+	return mergeTags(ac.Tags, ac.Provenance.Tags(ac))
+}
+
+// locateExisting tries to locate a usable image in the target that was
+// previously copied from the source image. Images are matched by their
+// provenance tags, if any, or their source image, and must be encrypted as a
+// new copy would be. They are never matched by name alone, as an image of the
+// same name may be a copy of another source.
+func (ac *AmiCopyImpl) locateExisting(ctx context.Context) (*ec2types.Image, error) {
+	var candidates [][]ec2types.Filter
+	if ac.Provenance != nil {
+		candidates = append(candidates, []ec2types.Filter{
+			{
+				Name:   aws.String("tag:" + ac.Provenance.Key(ProvenanceTagSourceAMI)),
+				Values: []string{aws.ToString(ac.input.SourceImageId)},
+			},
+			{
+				Name:   aws.String("tag:" + ac.Provenance.Key(ProvenanceTagSourceRegion)),
+				Values: []string{aws.ToString(ac.input.SourceRegion)},
+			},
+		})
+	}
+	for _, filters := range append(candidates, [][]ec2types.Filter{
+		{
+			{Name: aws.String("source-image-id"), Values: []string{aws.ToString(ac.input.SourceImageId)}},
+			{Name: aws.String("source-image-region"), Values: []string{aws.ToString(ac.input.SourceRegion)}},
+		},
+	}...) {
+		output, err := ac.EC2.DescribeImages(ctx, &ec2.DescribeImagesInput{
+			Owners:  []string{"self"},
+			Filters: filters,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range output.Images {
+			if (image.State == ec2types.ImageStateAvailable ||
+				image.State == ec2types.ImageStatePending) && ac.encryptedAsCopy(&image) {
+				return &image, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// encryptedAsCopy reports whether an existing image is encrypted as a new copy
+// would be. An encrypting copy must be encrypted with `KmsKeyId`, if set, and
+// any other copy must be encrypted as the source image is.
+func (ac *AmiCopyImpl) encryptedAsCopy(image *ec2types.Image) bool {
+	encrypted, kmsKeyIDs := imageEncryption(image)
+	if encrypted == nil {
+		return false
+	}
+	if !aws.ToBool(ac.input.Encrypted) {
+		sourceEncrypted, _ := imageEncryption(ac.SourceImage)
+		return sourceEncrypted != nil && *encrypted == *sourceEncrypted
+	}
+	if !*encrypted {
+		return false
+	}
+	if kmsKeyID := aws.ToString(ac.input.KmsKeyId); kmsKeyID != "" {
+		for _, imageKmsKeyID := range kmsKeyIDs {
+			// Images record the key ARN, which may have been given by ID.
+			if imageKmsKeyID != kmsKeyID && !strings.HasSuffix(imageKmsKeyID, ":key/"+kmsKeyID) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// TagsFromMap converts a map of tags to EC2 tags, sorted by key.
+func TagsFromMap(m map[string]string) (tags []ec2types.Tag) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tags = append(tags, ec2types.Tag{Key: aws.String(k), Value: aws.String(m[k])})
+	}
+	return tags
+}
+
+// mergeTags returns the union of the given tag sets. Where a key appears more
+// than once the last value wins.
+func mergeTags(sets ...[]ec2types.Tag) (tags []ec2types.Tag) {
+	index := map[string]int{}
+	for _, set := range sets {
+		for _, tag := range set {
+			key := aws.ToString(tag.Key)
+			if i, ok := index[key]; ok {
+				tags[i] = tag
+				continue
+			}
+			index[key] = len(tags)
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 // LocateSingleAMI tries to locate a single AMI for the given ID.
 func LocateSingleAMI(ctx context.Context, id string, ec2Conn *ec2.Client) (*ec2types.Image, error) {
 	if output, err := ec2Conn.DescribeImages(ctx, &ec2.DescribeImagesInput{
@@ -151,3 +715,59 @@ func LocateSingleAMI(ctx context.Context, id string, ec2Conn *ec2.Client) (*ec2t
 		return &output.Images[0], nil
 	}
 }
+
+// DeregisterImage deregisters the image and then deletes its backing EBS
+// snapshots. The IDs of the deleted snapshots are returned.
+func DeregisterImage(ctx context.Context, ec2Conn *ec2.Client, image *ec2types.Image) ([]string, error) {
+	if _, err := ec2Conn.DeregisterImage(ctx, &ec2.DeregisterImageInput{
+		ImageId: image.ImageId,
+	}); err != nil {
+		return nil, err
+	}
+
+	var snapshotIDs []string
+	for _, snapshotID := range imageSnapshotIDs(image) {
+		if _, err := ec2Conn.DeleteSnapshot(ctx, &ec2.DeleteSnapshotInput{
+			SnapshotId: aws.String(snapshotID),
+		}); err != nil {
+			return snapshotIDs, err
+		}
+		snapshotIDs = append(snapshotIDs, snapshotID)
+	}
+	return snapshotIDs, nil
+}
+
+// imageEncryption returns whether every EBS volume of the image is encrypted,
+// or nil if it has none, along with the KMS keys of the volumes.
+func imageEncryption(image *ec2types.Image) (encrypted *bool, kmsKeyIDs []string) {
+	for _, bdm := range imageEBSMappings(image) {
+		if encrypted == nil {
+			encrypted = aws.Bool(true)
+		}
+		*encrypted = *encrypted && aws.ToBool(bdm.Ebs.Encrypted)
+		if bdm.Ebs.KmsKeyId != nil {
+			kmsKeyIDs = append(kmsKeyIDs, *bdm.Ebs.KmsKeyId)
+		}
+	}
+	return encrypted, kmsKeyIDs
+}
+
+// imageEBSMappings returns the EBS block device mappings of the image.
+func imageEBSMappings(image *ec2types.Image) (mappings []ec2types.BlockDeviceMapping) {
+	for _, bdm := range image.BlockDeviceMappings {
+		if bdm.Ebs != nil {
+			mappings = append(mappings, bdm)
+		}
+	}
+	return mappings
+}
+
+// imageSnapshotIDs returns the IDs of the EBS snapshots backing the image.
+func imageSnapshotIDs(image *ec2types.Image) (snapshotIDs []string) {
+	for _, bdm := range image.BlockDeviceMappings {
+		if bdm.Ebs != nil && bdm.Ebs.SnapshotId != nil {
+			snapshotIDs = append(snapshotIDs, *bdm.Ebs.SnapshotId)
+		}
+	}
+	return snapshotIDs
+}
@@ -0,0 +1,119 @@
+package amicopy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestAmiCopyImpl_taggedInput(t *testing.T) {
+	source := &ec2types.Image{
+		OwnerId: aws.String("111111111111"),
+		Tags:    TagsFromMap(map[string]string{"Name": "base"}),
+	}
+
+	for _, tc := range []struct {
+		name          string
+		account       string
+		tags          map[string]string
+		filter        *TagFilter
+		copyImageTags bool
+		imageTags     int
+	}{
+		{"cross account", "222222222222", map[string]string{"Env": "prod"}, nil, false, 2},
+		{"same account", "111111111111", map[string]string{"Env": "prod"}, nil, true, 1},
+		{"same account override", "111111111111", map[string]string{"Name": "copy"}, nil, false, 1},
+		{"same account filtered", "111111111111", nil, &TagFilter{}, false, 1},
+		{"same account untagged", "111111111111", nil, nil, true, 0},
+	} {
+		ac := &AmiCopyImpl{
+			SourceImage: source,
+			Tags:        TagsFromMap(tc.tags),
+			TagFilter:   tc.filter,
+		}
+		ac.SetTargetAccountID(tc.account)
+		ac.SetInput(&ec2.CopyImageInput{SourceImageId: aws.String("ami-12345678")})
+
+		input := ac.taggedInput()
+		if aws.ToBool(input.CopyImageTags) != tc.copyImageTags {
+			t.Errorf("%s: expected CopyImageTags to be %t", tc.name, tc.copyImageTags)
+		}
+		var imageTags int
+		for _, spec := range input.TagSpecifications {
+			if spec.ResourceType == ec2types.ResourceTypeImage {
+				imageTags = len(spec.Tags)
+			}
+		}
+		if imageTags != tc.imageTags {
+			t.Errorf("%s: expected %d image tags, got %d", tc.name, tc.imageTags, imageTags)
+		}
+		if ac.Input().TagSpecifications != nil {
+			t.Errorf("%s: expected input to be unchanged", tc.name)
+		}
+	}
+}
+
+func TestAmiCopyImpl_existingTags(t *testing.T) {
+	ac := &AmiCopyImpl{
+		SourceImage: &ec2types.Image{Tags: TagsFromMap(map[string]string{"Name": "base"})},
+		Tags:        TagsFromMap(map[string]string{"Env": "prod"}),
+		Provenance:  &Provenance{Prefix: "ami-copy:", PluginVersion: "1.0.0"},
+	}
+	ac.SetInput(&ec2.CopyImageInput{SourceImageId: aws.String("ami-12345678")})
+
+	if tags := ac.tags(); len(tags) <= 2 {
+		t.Fatalf("expected new copies to have provenance tags, got %d tags", len(tags))
+	}
+	tags := ac.existingTags()
+	if len(tags) != 2 {
+		t.Fatalf("expected 2 tags, got %d", len(tags))
+	}
+	for _, tag := range tags {
+		if strings.HasPrefix(aws.ToString(tag.Key), "ami-copy:") {
+			t.Errorf("expected no provenance tags, got %s", aws.ToString(tag.Key))
+		}
+	}
+}
+
+func TestAmiCopyImpl_encryptedAsCopy(t *testing.T) {
+	image := func(encrypted bool, kmsKeyID string) *ec2types.Image {
+		ebs := &ec2types.EbsBlockDevice{Encrypted: aws.Bool(encrypted)}
+		if kmsKeyID != "" {
+			ebs.KmsKeyId = aws.String(kmsKeyID)
+		}
+		return &ec2types.Image{BlockDeviceMappings: []ec2types.BlockDeviceMapping{{Ebs: ebs}}}
+	}
+	const keyARN = "arn:aws:kms:us-east-1:111111111111:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+	for _, tc := range []struct {
+		name     string
+		source   *ec2types.Image
+		encrypt  bool
+		kmsKeyID string
+		existing *ec2types.Image
+		matches  bool
+	}{
+		{"unencrypted", image(false, ""), false, "", image(false, ""), true},
+		{"unencrypted source, encrypted existing", image(false, ""), false, "", image(true, keyARN), false},
+		{"encrypted source", image(true, keyARN), false, "", image(true, keyARN), true},
+		{"encrypt, unencrypted existing", image(false, ""), true, "", image(false, ""), false},
+		{"encrypt with default key", image(false, ""), true, "", image(true, keyARN), true},
+		{"encrypt with key ARN", image(false, ""), true, keyARN, image(true, keyARN), true},
+		{"encrypt with key ID", image(false, ""), true, "1234abcd-12ab-34cd-56ef-1234567890ab", image(true, keyARN), true},
+		{"encrypt with another key", image(false, ""), true, "abcd1234", image(true, keyARN), false},
+		{"no volumes", image(false, ""), false, "", &ec2types.Image{}, false},
+	} {
+		ac := &AmiCopyImpl{SourceImage: tc.source}
+		ac.SetInput(&ec2.CopyImageInput{
+			SourceImageId: aws.String("ami-12345678"),
+			Encrypted:     aws.Bool(tc.encrypt),
+			KmsKeyId:      aws.String(tc.kmsKeyID),
+		})
+		if actual := ac.encryptedAsCopy(tc.existing); actual != tc.matches {
+			t.Errorf("%s: expected %t, got %t", tc.name, tc.matches, actual)
+		}
+	}
+}
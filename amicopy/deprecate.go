@@ -0,0 +1,72 @@
+package amicopy
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Deprecation is the policy for discouraging the use of older images in the
+// lineage of a copy, as an alternative to pruning them.
+type Deprecation struct {
+	Lineage      Lineage
+	After        time.Duration
+	Disable      bool
+	ProtectInUse bool
+}
+
+// Deprecate schedules the deprecation of the older images in the lineage of an
+// image with the given tags `After` from now, and disables them if `Disable` is
+// set. The images of the run are given as the excluded IDs. Images that are
+// already scheduled for deprecation keep their schedule, and images in use by
+// instances or launch templates are not disabled when `ProtectInUse` is set.
+// The IDs of the deprecated and disabled images are returned.
+func (d *Deprecation) Deprecate(ctx context.Context, ec2Conn *ec2.Client, tags []ec2types.Tag, excludeIDs ...string) (
+	deprecated, disabled []string, err error) {
+
+	images, err := d.Lineage.Images(ctx, ec2Conn, tags, excludeIDs...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, image := range images {
+		if d.After > 0 && image.DeprecationTime == nil {
+			if err = DeprecateImage(ctx, ec2Conn, aws.ToString(image.ImageId), time.Now().Add(d.After)); err != nil {
+				return deprecated, disabled, err
+			}
+			deprecated = append(deprecated, aws.ToString(image.ImageId))
+		}
+
+		if !d.Disable {
+			continue
+		}
+		if d.ProtectInUse {
+			inUse, err := imageInUse(ctx, ec2Conn, aws.ToString(image.ImageId))
+			if err != nil {
+				return deprecated, disabled, err
+			}
+			if inUse {
+				continue
+			}
+		}
+		if _, err = ec2Conn.DisableImage(ctx, &ec2.DisableImageInput{
+			ImageId: image.ImageId,
+		}); err != nil {
+			return deprecated, disabled, err
+		}
+		disabled = append(disabled, aws.ToString(image.ImageId))
+	}
+	return deprecated, disabled, nil
+}
+
+// DeprecateImage schedules the deprecation of the image at the given time.
+func DeprecateImage(ctx context.Context, ec2Conn *ec2.Client, imageID string, at time.Time) error {
+	_, err := ec2Conn.EnableImageDeprecation(ctx, &ec2.EnableImageDeprecationInput{
+		ImageId:     aws.String(imageID),
+		DeprecateAt: aws.Time(at),
+	})
+	return err
+}
@@ -0,0 +1,96 @@
+package amicopy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// LaunchTemplates selects the launch templates in a target to update to use a
+// copied image, by name and/or by tags.
+type LaunchTemplates struct {
+	Names      []string
+	Tags       map[string]string
+	SetDefault bool
+}
+
+// Update creates a new version of each selected launch template, based on its
+// latest version, that uses the image. The new versions are made the default
+// if `SetDefault` is set. The updated templates are returned as `name:version`.
+func (lt *LaunchTemplates) Update(ctx context.Context, ec2Conn *ec2.Client, imageID string) ([]string, error) {
+	templates, err := lt.locate(ctx, ec2Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []string
+	for _, template := range templates {
+		output, err := ec2Conn.CreateLaunchTemplateVersion(ctx, &ec2.CreateLaunchTemplateVersionInput{
+			LaunchTemplateId:   template.LaunchTemplateId,
+			SourceVersion:      aws.String("$Latest"),
+			VersionDescription: aws.String(fmt.Sprintf("Image %s copied by ami-copy", imageID)),
+			LaunchTemplateData: &ec2types.RequestLaunchTemplateData{
+				ImageId: aws.String(imageID),
+			},
+		})
+		if err != nil {
+			return updated, err
+		}
+		version := aws.ToInt64(output.LaunchTemplateVersion.VersionNumber)
+
+		if lt.SetDefault {
+			if _, err = ec2Conn.ModifyLaunchTemplate(ctx, &ec2.ModifyLaunchTemplateInput{
+				LaunchTemplateId: template.LaunchTemplateId,
+				DefaultVersion:   aws.String(strconv.FormatInt(version, 10)),
+			}); err != nil {
+				return updated, err
+			}
+		}
+
+		updated = append(updated, fmt.Sprintf("%s:%d", aws.ToString(template.LaunchTemplateName), version))
+	}
+	return updated, nil
+}
+
+// locate returns the launch templates matching either the names or the tags.
+func (lt *LaunchTemplates) locate(ctx context.Context, ec2Conn *ec2.Client) ([]ec2types.LaunchTemplate, error) {
+	var inputs []*ec2.DescribeLaunchTemplatesInput
+	if len(lt.Names) > 0 {
+		inputs = append(inputs, &ec2.DescribeLaunchTemplatesInput{LaunchTemplateNames: lt.Names})
+	}
+	if len(lt.Tags) > 0 {
+		input := &ec2.DescribeLaunchTemplatesInput{}
+		for _, tag := range TagsFromMap(lt.Tags) {
+			input.Filters = append(input.Filters, ec2types.Filter{
+				Name:   aws.String("tag:" + aws.ToString(tag.Key)),
+				Values: []string{aws.ToString(tag.Value)},
+			})
+		}
+		inputs = append(inputs, input)
+	}
+
+	var (
+		templates []ec2types.LaunchTemplate
+		seen      = map[string]bool{}
+	)
+	for _, input := range inputs {
+		paginator := ec2.NewDescribeLaunchTemplatesPaginator(ec2Conn, input)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, template := range output.LaunchTemplates {
+				if id := aws.ToString(template.LaunchTemplateId); !seen[id] {
+					seen[id] = true
+					templates = append(templates, template)
+				}
+			}
+		}
+	}
+	return templates, nil
+}
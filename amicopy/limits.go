@@ -0,0 +1,27 @@
+package amicopy
+
+import (
+	"errors"
+
+	"github.com/aws/smithy-go"
+)
+
+// limitErrorCodes are the codes of errors returned when too many copies or
+// requests are in flight, which succeed when retried later.
+var limitErrorCodes = map[string]bool{
+	"ResourceLimitExceeded":    true,
+	"RequestLimitExceeded":     true,
+	"Throttling":               true,
+	"ThrottlingException":      true,
+	"TooManyRequestsException": true,
+}
+
+// IsLimitError reports whether the error is due to an AWS quota or throttling,
+// so that the copy can be retried once other copies have finished.
+func IsLimitError(err error) bool {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		return limitErrorCodes[ae.ErrorCode()]
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package amicopy
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Lineage identifies the images in a target that are versions of the same
+// image, by name prefix and/or by sharing the value of a tag.
+type Lineage struct {
+	NamePrefix string
+	TagKey     string
+}
+
+// Key identifies the lineage of an image with the given tags, being empty if
+// it is not part of one.
+func (l *Lineage) Key(tags []ec2types.Tag) string {
+	if l.NamePrefix == "" && l.TagKey == "" {
+		return ""
+	}
+	key := "name:" + l.NamePrefix
+	if l.TagKey != "" {
+		value := l.tagValue(tags)
+		if value == nil {
+			return ""
+		}
+		key += " tag:" + l.TagKey + "=" + aws.ToString(value)
+	}
+	return key
+}
+
+// tagValue returns the value of the lineage tag amongst the tags, if any.
+func (l *Lineage) tagValue(tags []ec2types.Tag) (value *string) {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == l.TagKey {
+			value = tag.Value
+		}
+	}
+	return value
+}
+
+// Images returns the images owned by the target in the lineage of an image
+// with the given tags, newest first. Only available images are returned, and
+// never those with the excluded IDs.
+func (l *Lineage) Images(ctx context.Context, ec2Conn *ec2.Client, tags []ec2types.Tag, excludeIDs ...string) (
+	[]ec2types.Image, error) {
+
+	var filters []ec2types.Filter
+	if l.NamePrefix != "" {
+		filters = append(filters, ec2types.Filter{
+			Name:   aws.String("name"),
+			Values: []string{l.NamePrefix + "*"},
+		})
+	}
+	if l.TagKey != "" {
+		value := l.tagValue(tags)
+		if value == nil {
+			// The image is not part of a lineage.
+			return nil, nil
+		}
+		filters = append(filters, ec2types.Filter{
+			Name:   aws.String("tag:" + l.TagKey),
+			Values: []string{aws.ToString(value)},
+		})
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	// Images that are still pending, or have failed, are not yet part of the
+	// lineage.
+	filters = append(filters, ec2types.Filter{
+		Name:   aws.String("state"),
+		Values: []string{string(ec2types.ImageStateAvailable)},
+	})
+
+	output, err := ec2Conn.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		Owners:  []string{"self"},
+		Filters: filters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var images []ec2types.Image
+IMAGES:
+	for _, image := range output.Images {
+		for _, id := range excludeIDs {
+			if aws.ToString(image.ImageId) == id {
+				continue IMAGES
+			}
+		}
+		images = append(images, image)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return imageCreationTime(&images[i]).After(imageCreationTime(&images[j]))
+	})
+	return images, nil
+}
+
+func imageCreationTime(image *ec2types.Image) time.Time {
+	t, _ := time.Parse(time.RFC3339, aws.ToString(image.CreationDate))
+	return t
+}
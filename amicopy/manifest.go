@@ -0,0 +1,251 @@
+package amicopy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestVersion is the version of the manifest document schema. Manifests
+// written before the schema was versioned are a bare array of copies.
+const ManifestVersion = 1
+
+// Manifest statuses of a copied image.
+const (
+	ManifestStatusPending      = "pending"
+	ManifestStatusCopied       = "copied"
+	ManifestStatusExisting     = "existing"
+	ManifestStatusFailed       = "failed"
+	ManifestStatusDeregistered = "deregistered"
+)
+
+// Manifest output formats.
+const (
+	ManifestFormatJSON   = "json"
+	ManifestFormatYAML   = "yaml"
+	ManifestFormatCSV    = "csv"
+	ManifestFormatTFVars = "tfvars"
+	ManifestFormatDotenv = "dotenv"
+)
+
+// ManifestFormats are the supported manifest output formats.
+var ManifestFormats = []string{
+	ManifestFormatJSON,
+	ManifestFormatYAML,
+	ManifestFormatCSV,
+	ManifestFormatTFVars,
+	ManifestFormatDotenv,
+}
+
+// Manifest is the versioned document describing every copy of a run.
+type Manifest struct {
+	Version int            `json:"version" yaml:"version"`
+	Copies  []*AmiManifest `json:"copies" yaml:"copies"`
+}
+
+// AmiManifest holds the data about the resulting copied image
+type AmiManifest struct {
+	BuildName     string     `json:"build_name,omitempty" yaml:"build_name,omitempty"`
+	AccountID     string     `json:"account_id" yaml:"account_id"`
+	Region        string     `json:"region" yaml:"region"`
+	ImageID       string     `json:"image_id" yaml:"image_id"`
+	SourceImageID string     `json:"source_image_id" yaml:"source_image_id"`
+	SourceRegion  string     `json:"source_region" yaml:"source_region"`
+	Name          string     `json:"name" yaml:"name"`
+	Encrypted     bool       `json:"encrypted" yaml:"encrypted"`
+	KmsKeyID      string     `json:"kms_key_id,omitempty" yaml:"kms_key_id,omitempty"`
+	SnapshotIDs   []string   `json:"snapshot_ids,omitempty" yaml:"snapshot_ids,omitempty"`
+	State         string     `json:"state,omitempty" yaml:"state,omitempty"`
+	Status        string     `json:"status" yaml:"status"`
+	StartTime     *time.Time `json:"start_time,omitempty" yaml:"start_time,omitempty"`
+	EndTime       *time.Time `json:"end_time,omitempty" yaml:"end_time,omitempty"`
+	TagsOnly      bool       `json:"tags_only" yaml:"tags_only"`
+	Error         string     `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Key uniquely identifies the copy across manifests.
+func (m *AmiManifest) Key() string {
+	return strings.Join([]string{m.BuildName, m.AccountID, m.Region, m.SourceRegion, m.SourceImageID}, "/")
+}
+
+// Usable reports whether the copy resulted in an image that can be used.
+func (m *AmiManifest) Usable() bool {
+	return m.ImageID != "" &&
+		(m.Status == ManifestStatusCopied || m.Status == ManifestStatusExisting)
+}
+
+// NewManifest returns a manifest document of the current version.
+func NewManifest(copies []*AmiManifest) *Manifest {
+	if copies == nil {
+		copies = []*AmiManifest{}
+	}
+	return &Manifest{Version: ManifestVersion, Copies: copies}
+}
+
+// ParseManifest parses a JSON or YAML manifest document of any version.
+func ParseManifest(data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		return manifest, json.Unmarshal(data, &manifest.Copies)
+	case bytes.HasPrefix(data, []byte("{")):
+		return manifest, json.Unmarshal(data, manifest)
+	default:
+		return manifest, yaml.Unmarshal(data, manifest)
+	}
+}
+
+// MergeManifests returns the existing manifests updated with the given ones.
+// Copies with the same key are replaced in place, and new copies are appended.
+func MergeManifests(existing, updates []*AmiManifest) []*AmiManifest {
+	var (
+		merged = make([]*AmiManifest, 0, len(existing)+len(updates))
+		index  = map[string]int{}
+	)
+	for _, m := range append(existing, updates...) {
+		if i, ok := index[m.Key()]; ok {
+			merged[i] = m
+			continue
+		}
+		index[m.Key()] = len(merged)
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+// Encode renders the manifest in the given format. Pretty printing only
+// affects the JSON format as the others are always human readable.
+//
+// The tfvars and dotenv formats only hold the usable copies, keyed by account
+// and region.
+func (m *Manifest) Encode(format string, pretty bool) ([]byte, error) {
+	switch format {
+	case ManifestFormatJSON:
+		if pretty {
+			return json.MarshalIndent(m, "", "  ")
+		}
+		return json.Marshal(m)
+	case ManifestFormatYAML:
+		return yaml.Marshal(m)
+	case ManifestFormatCSV:
+		return m.encodeCSV()
+	case ManifestFormatTFVars:
+		return m.encodeTFVars()
+	case ManifestFormatDotenv:
+		return m.encodeDotenv()
+	default:
+		return nil, fmt.Errorf("Unknown manifest format %q", format)
+	}
+}
+
+func (m *Manifest) encodeCSV() ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   = csv.NewWriter(&buf)
+	)
+	records := [][]string{{
+		"build_name", "account_id", "region", "image_id", "source_image_id", "source_region",
+		"name", "encrypted", "kms_key_id", "snapshot_ids", "state", "status",
+		"start_time", "end_time", "tags_only", "error",
+	}}
+	for _, c := range m.Copies {
+		records = append(records, []string{
+			c.BuildName, c.AccountID, c.Region, c.ImageID, c.SourceImageID, c.SourceRegion,
+			c.Name, strconv.FormatBool(c.Encrypted), c.KmsKeyID,
+			strings.Join(c.SnapshotIDs, " "), c.State, c.Status,
+			formatTime(c.StartTime), formatTime(c.EndTime),
+			strconv.FormatBool(c.TagsOnly), c.Error,
+		})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeTFVars renders an `amis` map of account IDs to a map of regions to
+// image IDs.
+func (m *Manifest) encodeTFVars() ([]byte, error) {
+	imageIDs, err := m.usableImageIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := map[string]cty.Value{}
+	for account, regions := range imageIDs {
+		images := map[string]cty.Value{}
+		for region, imageID := range regions {
+			images[region] = cty.StringVal(imageID)
+		}
+		accounts[account] = cty.MapVal(images)
+	}
+
+	amis := cty.MapValEmpty(cty.Map(cty.String))
+	if len(accounts) > 0 {
+		amis = cty.MapVal(accounts)
+	}
+
+	f := hclwrite.NewEmptyFile()
+	f.Body().SetAttributeValue("amis", amis)
+	return f.Bytes(), nil
+}
+
+// encodeDotenv renders an `AMI_<ACCOUNT>_<REGION>` variable per image.
+func (m *Manifest) encodeDotenv() ([]byte, error) {
+	imageIDs, err := m.usableImageIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for account, regions := range imageIDs {
+		for region, imageID := range regions {
+			name := strings.ToUpper(strings.ReplaceAll(
+				fmt.Sprintf("AMI_%s_%s", account, region), "-", "_"))
+			lines = append(lines, fmt.Sprintf("%s=%s\n", name, imageID))
+		}
+	}
+	sort.Strings(lines)
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// usableImageIDs returns the usable image IDs keyed by account and region.
+// Copies of different source AMIs to the same account and region cannot be
+// told apart by these keys, so are an error rather than overwriting each
+// other.
+func (m *Manifest) usableImageIDs() (map[string]map[string]string, error) {
+	images := map[string]map[string]string{}
+	for _, c := range m.Copies {
+		if !c.Usable() {
+			continue
+		}
+		if _, ok := images[c.AccountID]; !ok {
+			images[c.AccountID] = map[string]string{}
+		}
+		if imageID, ok := images[c.AccountID][c.Region]; ok && imageID != c.ImageID {
+			return nil, fmt.Errorf("Copies %s and %s are both in %s on account %s, "+
+				"use a json, yaml or csv manifest to record copies of several source AMIs",
+				imageID, c.ImageID, c.Region, c.AccountID)
+		}
+		images[c.AccountID][c.Region] = c.ImageID
+	}
+	return images, nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,86 @@
+package amicopy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	expected := []*AmiManifest{
+		{AccountID: "123456789012", Region: "eu-west-1", ImageID: "ami-1"},
+	}
+
+	for name, data := range map[string]string{
+		"legacy":    `[{"account_id":"123456789012","region":"eu-west-1","image_id":"ami-1"}]`,
+		"versioned": `{"version":1,"copies":[{"account_id":"123456789012","region":"eu-west-1","image_id":"ami-1"}]}`,
+	} {
+		manifest, err := ParseManifest([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		if !reflect.DeepEqual(manifest.Copies, expected) {
+			t.Fatalf("%s: bad: %#v", name, manifest.Copies)
+		}
+	}
+}
+
+func TestManifest_Encode(t *testing.T) {
+	manifest := NewManifest([]*AmiManifest{
+		{BuildName: "base", AccountID: "123456789012", Region: "eu-west-1", ImageID: "ami-1", Status: ManifestStatusCopied},
+		{BuildName: "base", AccountID: "123456789012", Region: "us-east-1", ImageID: "ami-2", Status: ManifestStatusExisting},
+		{BuildName: "base", AccountID: "456789012345", Region: "eu-west-1", Status: ManifestStatusFailed, Error: "boom"},
+	})
+
+	for format, expected := range map[string]string{
+		ManifestFormatTFVars: `amis = {
+  "123456789012" = {
+    eu-west-1 = "ami-1"
+    us-east-1 = "ami-2"
+  }
+}
+`,
+		ManifestFormatDotenv: `AMI_123456789012_EU_WEST_1=ami-1
+AMI_123456789012_US_EAST_1=ami-2
+`,
+		ManifestFormatCSV: `build_name,account_id,region,image_id,source_image_id,source_region,name,encrypted,kms_key_id,snapshot_ids,state,status,start_time,end_time,tags_only,error
+base,123456789012,eu-west-1,ami-1,,,,false,,,,copied,,,false,
+base,123456789012,us-east-1,ami-2,,,,false,,,,existing,,,false,
+base,456789012345,eu-west-1,,,,,false,,,,failed,,,false,boom
+`,
+	} {
+		encoded, err := manifest.Encode(format, false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", format, err)
+		}
+		if string(encoded) != expected {
+			t.Fatalf("%s: bad:\n%s", format, encoded)
+		}
+	}
+}
+
+func TestMergeManifests(t *testing.T) {
+	var (
+		a1 = &AmiManifest{BuildName: "a", AccountID: "123456789012", Region: "eu-west-1", Status: ManifestStatusFailed}
+		a2 = &AmiManifest{BuildName: "a", AccountID: "123456789012", Region: "eu-west-1", Status: ManifestStatusCopied}
+		b1 = &AmiManifest{BuildName: "b", AccountID: "123456789012", Region: "eu-west-1", Status: ManifestStatusCopied}
+		c1 = &AmiManifest{BuildName: "c", AccountID: "123456789012", Region: "eu-west-1", Status: ManifestStatusCopied}
+	)
+
+	merged := MergeManifests([]*AmiManifest{a1, b1}, []*AmiManifest{c1, a2})
+	if expected := []*AmiManifest{a2, b1, c1}; !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("bad: %#v", merged)
+	}
+}
+
+func TestManifest_Encode_collision(t *testing.T) {
+	manifest := NewManifest([]*AmiManifest{
+		{AccountID: "123456789012", Region: "eu-west-1", ImageID: "ami-1", SourceImageID: "ami-a", Status: ManifestStatusCopied},
+		{AccountID: "123456789012", Region: "eu-west-1", ImageID: "ami-2", SourceImageID: "ami-b", Status: ManifestStatusCopied},
+	})
+
+	for _, format := range []string{ManifestFormatTFVars, ManifestFormatDotenv} {
+		if _, err := manifest.Encode(format, false); err == nil {
+			t.Fatalf("%s: expected an error for copies to the same account and region", format)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package amicopy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// maxFilterValues is the most values DescribeImages accepts for a filter.
+const maxFilterValues = 200
+
+// Poller waits for the images in a single account and region to become
+// available. Every image waited on is described by one DescribeImages call per
+// poll, rather than a call per image. Polling starts with the first wait and
+// stops once nothing is waited on.
+type Poller struct {
+	EC2       *ec2.Client
+	AccountID string
+	Region    string
+	Interval  time.Duration
+	Ui        packer.Ui
+
+	mu        sync.Mutex
+	waiters   map[string][]*waiter
+	running   bool
+	group     *Pollers
+	progress  map[string]int
+	waited    int
+	available int
+}
+
+// waiter is a single wait on an image.
+type waiter struct {
+	deadline time.Time
+	done     chan waitResult
+}
+
+type waitResult struct {
+	image *ec2types.Image
+	err   error
+}
+
+// Wait blocks until the image is available, returning it. An error is returned
+// as soon as the image fails or is deregistered, the timeout passes or the
+// context is cancelled, along with the last state seen of the image, if any.
+func (p *Poller) Wait(ctx context.Context, imageID string, timeout time.Duration) (*ec2types.Image, error) {
+	w := &waiter{
+		deadline: time.Now().Add(timeout),
+		done:     make(chan waitResult, 1),
+	}
+
+	p.mu.Lock()
+	if p.waiters == nil {
+		p.waiters = map[string][]*waiter{}
+	}
+	p.waiters[imageID] = append(p.waiters[imageID], w)
+	p.waited++
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+	p.mu.Unlock()
+
+	select {
+	case result := <-w.done:
+		return result.image, result.err
+	case <-ctx.Done():
+		p.remove(imageID, w)
+		return nil, ctx.Err()
+	}
+}
+
+// remove stops waiting on the image for the waiter.
+func (p *Poller) remove(imageID string, w *waiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	waiters := p.waiters[imageID]
+	for i := range waiters {
+		if waiters[i] == w {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(p.waiters, imageID)
+	} else {
+		p.waiters[imageID] = waiters
+	}
+}
+
+// run polls until there are no images left to wait on.
+func (p *Poller) run() {
+	for {
+		p.mu.Lock()
+		imageIDs := make([]string, 0, len(p.waiters))
+		for imageID := range p.waiters {
+			imageIDs = append(imageIDs, imageID)
+		}
+		if len(imageIDs) == 0 {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		sort.Strings(imageIDs)
+		images, err := p.describe(context.Background(), imageIDs)
+
+		// Progress is only informational, so failing to find it is ignored.
+		progress, _ := snapshotProgress(context.Background(), p.EC2, images)
+
+		p.mu.Lock()
+		p.settle(images, err)
+		p.progress = progress
+		p.mu.Unlock()
+
+		if p.group != nil {
+			p.group.report()
+		} else if p.Ui != nil {
+			if progress := p.Progress(); progress.Pending > 0 {
+				p.Ui.Say(progress.String())
+			}
+		}
+		time.Sleep(p.Interval)
+	}
+}
+
+// describe returns the images by their IDs. Images that are not yet visible
+// are missing rather than an error.
+func (p *Poller) describe(ctx context.Context, imageIDs []string) (map[string]*ec2types.Image, error) {
+	images := map[string]*ec2types.Image{}
+	for start := 0; start < len(imageIDs); start += maxFilterValues {
+		end := start + maxFilterValues
+		if end > len(imageIDs) {
+			end = len(imageIDs)
+		}
+		paginator := ec2.NewDescribeImagesPaginator(p.EC2, &ec2.DescribeImagesInput{
+			Filters: []ec2types.Filter{
+				{
+					Name:   aws.String("image-id"),
+					Values: imageIDs[start:end],
+				},
+			},
+		})
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for i := range output.Images {
+				images[aws.ToString(output.Images[i].ImageId)] = &output.Images[i]
+			}
+		}
+	}
+	return images, nil
+}
+
+// settle completes the waits on images that became available, failed or timed
+// out, returning how many images are still pending. A failure to describe the
+// images is only returned to waits that time out.
+func (p *Poller) settle(images map[string]*ec2types.Image, describeErr error) (pending int) {
+	now := time.Now()
+	for imageID, waiters := range p.waiters {
+		image := images[imageID]
+
+		var result *waitResult
+		switch {
+		case image != nil && image.State == ec2types.ImageStateAvailable:
+			result = &waitResult{image: image}
+		case image != nil && imageFailed(image):
+			result = &waitResult{image: image, err: fmt.Errorf("Image %s on account %s is %s: %s",
+				imageID, p.AccountID, image.State, stateReason(image))}
+		}
+
+		var remaining []*waiter
+		for _, w := range waiters {
+			switch {
+			case result != nil:
+				if result.err == nil {
+					p.available++
+				}
+				w.done <- *result
+			case now.After(w.deadline):
+				err := fmt.Errorf("Timed out waiting for image %s to copy to account %s", imageID, p.AccountID)
+				if describeErr != nil {
+					err = fmt.Errorf("%s: %s", err, describeErr)
+				}
+				w.done <- waitResult{image: image, err: err}
+			default:
+				remaining = append(remaining, w)
+			}
+		}
+
+		if len(remaining) == 0 {
+			delete(p.waiters, imageID)
+		} else {
+			p.waiters[imageID] = remaining
+			pending++
+		}
+	}
+	return pending
+}
+
+// Progress returns the progress of the copies waited on.
+func (p *Poller) Progress() Progress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	progress := Progress{
+		Waited:         p.waited,
+		Available:      p.available,
+		Pending:        len(p.waiters),
+		Slowest:        p.AccountID + "/" + p.Region,
+		SlowestPercent: 100,
+	}
+	for imageID := range p.waiters {
+		if percent := p.progress[imageID]; percent < progress.SlowestPercent {
+			progress.SlowestPercent = percent
+		}
+	}
+	return progress
+}
+
+// imageFailed reports whether the image will never become available.
+func imageFailed(image *ec2types.Image) bool {
+	switch image.State {
+	case ec2types.ImageStateFailed, ec2types.ImageStateDeregistered,
+		ec2types.ImageStateInvalid, ec2types.ImageStateError:
+		return true
+	}
+	return false
+}
+
+// Pollers shares a Poller between all the copies to the same account and
+// region.
+type Pollers struct {
+	Interval time.Duration
+	Ui       packer.Ui
+
+	// Total is the number of copies that will be waited on, if known, so that
+	// progress counts the copies not yet submitted.
+	Total int
+
+	mu         sync.Mutex
+	pollers    map[string]*Poller
+	lastReport time.Time
+}
+
+// Get returns the Poller for the account and region, creating it with the
+// client if there is none yet.
+func (ps *Pollers) Get(accountID, region string, ec2Conn *ec2.Client) *Poller {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	key := accountID + "/" + region
+	if poller, ok := ps.pollers[key]; ok {
+		return poller
+	}
+	if ps.pollers == nil {
+		ps.pollers = map[string]*Poller{}
+	}
+	ps.pollers[key] = &Poller{
+		EC2:       ec2Conn,
+		AccountID: accountID,
+		Region:    region,
+		Interval:  ps.Interval,
+		Ui:        ps.Ui,
+		group:     ps,
+	}
+	return ps.pollers[key]
+}
+
+// Progress returns the progress of the copies waited on by every Poller.
+func (ps *Pollers) Progress() Progress {
+	ps.mu.Lock()
+	pollers := make([]*Poller, 0, len(ps.pollers))
+	for _, poller := range ps.pollers {
+		pollers = append(pollers, poller)
+	}
+	ps.mu.Unlock()
+
+	var progress Progress
+	for _, poller := range pollers {
+		progress.add(poller.Progress())
+	}
+	if progress.Waited < ps.Total {
+		progress.Waited = ps.Total
+	}
+	return progress
+}
+
+// report says the progress of all the copies waited on, at most once per
+// interval however many pollers there are.
+func (ps *Pollers) report() {
+	ps.mu.Lock()
+	if time.Since(ps.lastReport) < ps.Interval || ps.Ui == nil {
+		ps.mu.Unlock()
+		return
+	}
+	ps.lastReport = time.Now()
+	ps.mu.Unlock()
+
+	if progress := ps.Progress(); progress.Pending > 0 {
+		ps.Ui.Say(progress.String())
+	}
+}
@@ -0,0 +1,98 @@
+package amicopy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestPoller_settle(t *testing.T) {
+	var (
+		future = time.Now().Add(time.Hour)
+		past   = time.Now().Add(-time.Minute)
+	)
+	newWaiter := func(deadline time.Time) *waiter {
+		return &waiter{deadline: deadline, done: make(chan waitResult, 1)}
+	}
+
+	available := newWaiter(future)
+	failed := newWaiter(future)
+	pending := newWaiter(future)
+	missing := newWaiter(future)
+	timedOut := newWaiter(past)
+
+	p := &Poller{
+		AccountID: "123456789012",
+		waiters: map[string][]*waiter{
+			"ami-available": {available},
+			"ami-failed":    {failed},
+			"ami-pending":   {pending, timedOut},
+			"ami-missing":   {missing},
+		},
+	}
+	images := map[string]*ec2types.Image{
+		"ami-available": {ImageId: aws.String("ami-available"), State: ec2types.ImageStateAvailable},
+		"ami-failed": {
+			ImageId:     aws.String("ami-failed"),
+			State:       ec2types.ImageStateFailed,
+			StateReason: &ec2types.StateReason{Code: aws.String("Client.InternalError"), Message: aws.String("copy failed")},
+		},
+		"ami-pending": {ImageId: aws.String("ami-pending"), State: ec2types.ImageStatePending},
+	}
+
+	if remaining := p.settle(images, errors.New("throttled")); remaining != 2 {
+		t.Errorf("expected 2 images pending, got %d", remaining)
+	}
+
+	if result := <-available.done; result.err != nil || result.image == nil {
+		t.Errorf("expected available image, got %v", result.err)
+	}
+	if result := <-failed.done; result.err == nil || result.image == nil {
+		t.Error("expected failed image to return an error")
+	}
+	if result := <-timedOut.done; result.err == nil {
+		t.Error("expected timed out wait to return an error")
+	}
+	for name, w := range map[string]*waiter{"pending": pending, "missing": missing} {
+		select {
+		case <-w.done:
+			t.Errorf("expected %s image to still be waited on", name)
+		default:
+		}
+	}
+	if len(p.waiters["ami-pending"]) != 1 {
+		t.Errorf("expected one waiter left on the pending image, got %d", len(p.waiters["ami-pending"]))
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var progress Progress
+	for _, other := range []Progress{
+		{Waited: 20, Available: 8, Pending: 12, Slowest: "123456789012/us-east-1", SlowestPercent: 71},
+		{Waited: 5, Available: 5},
+		{Waited: 15, Available: 0, Pending: 15, Slowest: "123456789012/eu-west-1", SlowestPercent: 43},
+	} {
+		progress.add(other)
+	}
+
+	expected := "13/40 copies available, slowest: 123456789012/eu-west-1 at 43%"
+	if actual := progress.String(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if actual := (Progress{Waited: 2, Available: 2}).String(); actual != "2/2 copies available" {
+		t.Errorf("unexpected progress %q", actual)
+	}
+}
+
+func TestPollers_Progress(t *testing.T) {
+	ps := &Pollers{Total: 10}
+	poller := ps.Get("123456789012", "us-east-1", nil)
+	poller.waited, poller.available = 2, 2
+
+	if actual := ps.Progress().String(); actual != "2/10 copies available" {
+		t.Errorf("unexpected progress %q", actual)
+	}
+}
@@ -0,0 +1,104 @@
+package amicopy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Progress summarises the copies waited on by one or more pollers.
+type Progress struct {
+	Waited    int
+	Available int
+	Pending   int
+
+	// Slowest is the account and region of the pending copy least far along,
+	// and SlowestPercent how far along it is.
+	Slowest        string
+	SlowestPercent int
+}
+
+// add combines the progress of another poller.
+func (pr *Progress) add(other Progress) {
+	pr.Waited += other.Waited
+	pr.Available += other.Available
+	if other.Pending > 0 && (pr.Pending == 0 || other.SlowestPercent < pr.SlowestPercent) {
+		pr.Slowest, pr.SlowestPercent = other.Slowest, other.SlowestPercent
+	}
+	pr.Pending += other.Pending
+}
+
+// String describes the progress, e.g.
+// "12/40 copies available, slowest: 123456789012/eu-west-1 at 43%".
+func (pr Progress) String() string {
+	s := fmt.Sprintf("%d/%d copies available", pr.Available, pr.Waited)
+	if pr.Pending > 0 {
+		s += fmt.Sprintf(", slowest: %s at %d%%", pr.Slowest, pr.SlowestPercent)
+	}
+	return s
+}
+
+// snapshotProgress returns how far along the copy of each pending image is, as
+// the percentage complete of its least complete snapshot. Images whose
+// snapshots are not yet known are at 0%.
+func snapshotProgress(ctx context.Context, ec2Conn *ec2.Client, images map[string]*ec2types.Image) (map[string]int, error) {
+	var (
+		progress    = map[string]int{}
+		snapshotIDs []string
+		imageIDs    = map[string][]string{}
+	)
+	for imageID, image := range images {
+		if image.State != ec2types.ImageStatePending {
+			continue
+		}
+		progress[imageID] = 0
+		for _, snapshotID := range imageSnapshotIDs(image) {
+			snapshotIDs = append(snapshotIDs, snapshotID)
+			imageIDs[snapshotID] = append(imageIDs[snapshotID], imageID)
+		}
+	}
+
+	snapshotPercent := map[string]int{}
+	for start := 0; start < len(snapshotIDs); start += maxFilterValues {
+		end := start + maxFilterValues
+		if end > len(snapshotIDs) {
+			end = len(snapshotIDs)
+		}
+		paginator := ec2.NewDescribeSnapshotsPaginator(ec2Conn, &ec2.DescribeSnapshotsInput{
+			Filters: []ec2types.Filter{
+				{
+					Name:   aws.String("snapshot-id"),
+					Values: snapshotIDs[start:end],
+				},
+			},
+		})
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return progress, err
+			}
+			for _, snapshot := range output.Snapshots {
+				percent, _ := strconv.Atoi(strings.TrimSuffix(aws.ToString(snapshot.Progress), "%"))
+				snapshotPercent[aws.ToString(snapshot.SnapshotId)] = percent
+			}
+		}
+	}
+
+	for imageID := range progress {
+		percent := -1
+		for _, snapshotID := range imageSnapshotIDs(images[imageID]) {
+			if p := snapshotPercent[snapshotID]; percent < 0 || p < percent {
+				percent = p
+			}
+		}
+		if percent > 0 {
+			progress[imageID] = percent
+		}
+	}
+	return progress, nil
+}
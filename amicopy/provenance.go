@@ -0,0 +1,57 @@
+package amicopy
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Provenance tag keys, relative to the prefix.
+const (
+	ProvenanceTagSourceAccount = "source-account"
+	ProvenanceTagSourceAMI     = "source-ami"
+	ProvenanceTagSourceRegion  = "source-region"
+	ProvenanceTagBuildName     = "build-name"
+	ProvenanceTagBuildUUID     = "build-uuid"
+	ProvenanceTagVersion       = "plugin-version"
+	ProvenanceTagCopiedAt      = "copied-at"
+)
+
+// Provenance describes where copies came from, as tags under `Prefix`.
+type Provenance struct {
+	Prefix        string
+	BuildUUID     string
+	PluginVersion string
+}
+
+// Key returns the full key of the provenance tag.
+func (p *Provenance) Key(tag string) string {
+	return p.Prefix + tag
+}
+
+// Tags returns the provenance tags of a copy. Tags without a value are
+// omitted.
+func (p *Provenance) Tags(ac *AmiCopyImpl) (tags []ec2types.Tag) {
+	for _, tag := range [][2]string{
+		{ProvenanceTagSourceAccount, aws.ToString(ac.SourceImage.OwnerId)},
+		{ProvenanceTagSourceAMI, aws.ToString(ac.input.SourceImageId)},
+		{ProvenanceTagSourceRegion, aws.ToString(ac.input.SourceRegion)},
+		{ProvenanceTagBuildName, ac.BuildName},
+		{ProvenanceTagBuildUUID, p.BuildUUID},
+		{ProvenanceTagVersion, p.PluginVersion},
+		{ProvenanceTagCopiedAt, copiedAt(ac.startTime)},
+	} {
+		if tag[1] != "" {
+			tags = append(tags, ec2types.Tag{Key: aws.String(p.Key(tag[0])), Value: aws.String(tag[1])})
+		}
+	}
+	return tags
+}
+
+func copiedAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
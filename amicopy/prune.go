@@ -0,0 +1,126 @@
+package amicopy
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Retention is the policy for pruning older images in the lineage of a copy.
+// An older image is kept if it is amongst the newest `Count` images (including
+// the copies) or is younger than `Days`, considering only the bounds that are
+// set.
+type Retention struct {
+	Lineage      Lineage
+	Count        int
+	Days         int
+	ProtectInUse bool
+}
+
+// Prune deregisters the older images in the lineage of an image with the given
+// tags that fall outside the retention policy, deleting their snapshots. The
+// images of the run are given as the excluded IDs, and `copies` is how many of
+// them are in the lineage. Images in use by instances or launch templates are
+// skipped when `ProtectInUse` is set. The IDs of the pruned images are
+// returned.
+func (r *Retention) Prune(ctx context.Context, ec2Conn *ec2.Client, tags []ec2types.Tag, copies int,
+	excludeIDs ...string) ([]string, error) {
+
+	images, err := r.Lineage.Images(ctx, ec2Conn, tags, excludeIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []string
+	for i := range images {
+		image := &images[i]
+		if r.retained(i, copies, image) {
+			continue
+		}
+		if r.ProtectInUse {
+			inUse, err := imageInUse(ctx, ec2Conn, aws.ToString(image.ImageId))
+			if err != nil {
+				return pruned, err
+			}
+			if inUse {
+				continue
+			}
+		}
+		if _, err = DeregisterImage(ctx, ec2Conn, image); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, aws.ToString(image.ImageId))
+	}
+	return pruned, nil
+}
+
+// retained reports whether the older image at the given index, newest first,
+// is kept by the policy alongside the run's `copies` in the lineage.
+func (r *Retention) retained(i, copies int, image *ec2types.Image) bool {
+	// The copies themselves count towards the retained images.
+	if r.Count > 0 && i+copies < r.Count {
+		return true
+	}
+	if r.Days > 0 && time.Since(imageCreationTime(image)) < time.Duration(r.Days)*24*time.Hour {
+		return true
+	}
+	return false
+}
+
+// imageInUse reports whether the image is used by any instance that has not
+// been terminated, or by any version of any launch template, as Auto Scaling
+// groups may be pinned to older versions.
+func imageInUse(ctx context.Context, ec2Conn *ec2.Client, imageID string) (bool, error) {
+	imageFilter := ec2types.Filter{
+		Name:   aws.String("image-id"),
+		Values: []string{imageID},
+	}
+
+	// Filters are applied per page, so a match may be on any page.
+	instances := ec2.NewDescribeInstancesPaginator(ec2Conn, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			imageFilter,
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: []string{"pending", "running", "shutting-down", "stopping", "stopped"},
+			},
+		},
+	})
+	for instances.HasMorePages() {
+		output, err := instances.NextPage(ctx)
+		if err != nil {
+			return false, err
+		}
+		if len(output.Reservations) > 0 {
+			return true, nil
+		}
+	}
+
+	templates := ec2.NewDescribeLaunchTemplatesPaginator(ec2Conn, &ec2.DescribeLaunchTemplatesInput{})
+	for templates.HasMorePages() {
+		output, err := templates.NextPage(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, template := range output.LaunchTemplates {
+			versions := ec2.NewDescribeLaunchTemplateVersionsPaginator(ec2Conn,
+				&ec2.DescribeLaunchTemplateVersionsInput{
+					LaunchTemplateId: template.LaunchTemplateId,
+					Filters:          []ec2types.Filter{imageFilter},
+				})
+			for versions.HasMorePages() {
+				output, err := versions.NextPage(ctx)
+				if err != nil {
+					return false, err
+				}
+				if len(output.LaunchTemplateVersions) > 0 {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
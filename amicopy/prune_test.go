@@ -0,0 +1,37 @@
+package amicopy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestRetention_retained(t *testing.T) {
+	var (
+		recent = &ec2types.Image{CreationDate: aws.String(time.Now().Add(-24 * time.Hour).Format(time.RFC3339))}
+		old    = &ec2types.Image{CreationDate: aws.String(time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339))}
+	)
+
+	for _, tc := range []struct {
+		name     string
+		policy   Retention
+		index    int
+		copies   int
+		image    *ec2types.Image
+		expected bool
+	}{
+		{"within count", Retention{Count: 3}, 1, 1, old, true},
+		{"beyond count", Retention{Count: 3}, 2, 1, recent, false},
+		{"within days", Retention{Days: 7}, 5, 1, recent, true},
+		{"beyond days", Retention{Days: 7}, 0, 1, old, false},
+		{"beyond count within days", Retention{Count: 1, Days: 7}, 0, 1, recent, true},
+		{"beyond count and days", Retention{Count: 1, Days: 7}, 0, 1, old, false},
+		{"beyond count with several copies", Retention{Count: 3}, 1, 2, old, false},
+	} {
+		if retained := tc.policy.retained(tc.index, tc.copies, tc.image); retained != tc.expected {
+			t.Errorf("%s: expected retained to be %t", tc.name, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package amicopy
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+)
+
+// Retry modes, as in the AWS SDK.
+const (
+	RetryModeStandard = "standard"
+	RetryModeAdaptive = "adaptive"
+)
+
+// Defaults for retrying AWS API calls.
+const (
+	DefaultMaxRetries = 10
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = 20 * time.Second
+)
+
+// DefaultRetryableErrorCodes are retried on top of the throttling, 5xx and
+// transient errors the AWS SDK retries. `UnauthorizedOperation` is returned
+// while a newly assumed role propagates.
+var DefaultRetryableErrorCodes = []string{"UnauthorizedOperation"}
+
+// RetryPolicy is how AWS API calls are retried.
+type RetryPolicy struct {
+	MaxRetries          int
+	MinBackoff          time.Duration
+	MaxBackoff          time.Duration
+	RetryableErrorCodes []string
+	Mode                string
+}
+
+// Retryer returns a new AWS SDK retryer for the policy, to use as
+// `aws.Config.Retryer`.
+func (rp *RetryPolicy) Retryer() aws.Retryer {
+	standard := func(o *awsretry.StandardOptions) {
+		o.MaxAttempts = rp.MaxRetries + 1
+		o.MaxBackoff = rp.MaxBackoff
+		o.Backoff = awsretry.BackoffDelayerFunc(func(attempt int, _ error) (time.Duration, error) {
+			return rp.backoff(attempt, rand.Float64()), nil
+		})
+		o.Retryables = append(o.Retryables, awsretry.IsErrorRetryableFunc(rp.retryable))
+		// Every copy to an account and region shares the retryer, so a
+		// retry quota would fail calls that would otherwise succeed once
+		// retried.
+		o.RateLimiter = ratelimit.None
+	}
+
+	if rp.Mode == RetryModeAdaptive {
+		return awsretry.NewAdaptiveMode(func(o *awsretry.AdaptiveModeOptions) {
+			o.StandardOptions = append(o.StandardOptions, standard)
+		})
+	}
+	return awsretry.NewStandard(standard)
+}
+
+// backoff returns the delay before the retry attempt, being an exponential
+// backoff from `MinBackoff` up to `MaxBackoff` with full jitter above the
+// minimum.
+func (rp *RetryPolicy) backoff(attempt int, jitter float64) time.Duration {
+	delay := rp.MaxBackoff
+	if attempt < 32 {
+		if exp := rp.MinBackoff << (attempt - 1); exp > 0 && exp < delay {
+			delay = exp
+		}
+	}
+	return rp.MinBackoff + time.Duration(jitter*float64(delay-rp.MinBackoff))
+}
+
+// retryable reports whether the error has one of the `RetryableErrorCodes`,
+// leaving other errors to the SDK.
+func (rp *RetryPolicy) retryable(err error) aws.Ternary {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		for _, code := range rp.RetryableErrorCodes {
+			if ae.ErrorCode() == code {
+				return aws.TrueTernary
+			}
+		}
+	}
+	return aws.UnknownTernary
+}
@@ -0,0 +1,48 @@
+package amicopy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go"
+)
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := &RetryPolicy{MinBackoff: time.Second, MaxBackoff: 20 * time.Second}
+
+	for _, tc := range []struct {
+		attempt  int
+		jitter   float64
+		expected time.Duration
+	}{
+		{1, 1, time.Second},
+		{2, 1, 2 * time.Second},
+		{3, 0.5, 2500 * time.Millisecond},
+		{4, 0, time.Second},
+		{6, 1, 20 * time.Second},
+		{100, 1, 20 * time.Second},
+	} {
+		if actual := policy.backoff(tc.attempt, tc.jitter); actual != tc.expected {
+			t.Errorf("attempt %d with jitter %v: expected %s, got %s", tc.attempt, tc.jitter, tc.expected, actual)
+		}
+	}
+}
+
+func TestRetryPolicy_retryable(t *testing.T) {
+	policy := &RetryPolicy{RetryableErrorCodes: DefaultRetryableErrorCodes}
+
+	for _, tc := range []struct {
+		err      error
+		expected aws.Ternary
+	}{
+		{&smithy.GenericAPIError{Code: "UnauthorizedOperation"}, aws.TrueTernary},
+		{&smithy.GenericAPIError{Code: "InvalidAMIID.Malformed"}, aws.UnknownTernary},
+		{errors.New("connection reset"), aws.UnknownTernary},
+	} {
+		if actual := policy.retryable(tc.err); actual != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.err, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package amicopy
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// SSMParameter is a Parameter Store parameter to publish a copied image ID to.
+type SSMParameter struct {
+	SSM       *ssm.Client
+	Name      string
+	Overwrite bool
+	Labels    []string
+	Tags      []ssmtypes.Tag
+}
+
+// Publish writes the image ID to the parameter as a new version, labels that
+// version and tags the parameter. The new version is returned.
+func (p *SSMParameter) Publish(ctx context.Context, imageID string) (int64, error) {
+	output, err := p.SSM.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(p.Name),
+		Value:     aws.String(imageID),
+		Type:      ssmtypes.ParameterTypeString,
+		DataType:  aws.String("aws:ec2:image"),
+		Overwrite: aws.Bool(p.Overwrite),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(p.Labels) > 0 {
+		if _, err = p.SSM.LabelParameterVersion(ctx, &ssm.LabelParameterVersionInput{
+			Name:             aws.String(p.Name),
+			ParameterVersion: aws.Int64(output.Version),
+			Labels:           p.Labels,
+		}); err != nil {
+			return output.Version, err
+		}
+	}
+
+	// Tags cannot be set by PutParameter when overwriting.
+	if len(p.Tags) > 0 {
+		if _, err = p.SSM.AddTagsToResource(ctx, &ssm.AddTagsToResourceInput{
+			ResourceType: ssmtypes.ResourceTypeForTaggingParameter,
+			ResourceId:   aws.String(p.Name),
+			Tags:         p.Tags,
+		}); err != nil {
+			return output.Version, err
+		}
+	}
+
+	return output.Version, nil
+}
@@ -0,0 +1,59 @@
+package amicopy
+
+import (
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// TagFilter selects and renames the source image tags copied to the targets.
+type TagFilter struct {
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+	Renames []TagRename
+}
+
+// TagRename renames tag keys matching `Pattern` to `Replacement`, which may
+// refer to submatches of the pattern, e.g. `$1`.
+type TagRename struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// Apply returns the tags whose keys match an `Include` pattern, if there are
+// any, and do not match an `Exclude` pattern, renamed by the first matching
+// rename rule. A nil filter returns the tags as they are.
+func (f *TagFilter) Apply(tags []ec2types.Tag) []ec2types.Tag {
+	if f == nil {
+		return tags
+	}
+
+	var filtered []ec2types.Tag
+	for _, tag := range tags {
+		key := aws.ToString(tag.Key)
+		if len(f.Include) > 0 && !matchAny(f.Include, key) {
+			continue
+		}
+		if matchAny(f.Exclude, key) {
+			continue
+		}
+		for _, rename := range f.Renames {
+			if rename.Pattern.MatchString(key) {
+				key = rename.Pattern.ReplaceAllString(key, rename.Replacement)
+				break
+			}
+		}
+		filtered = append(filtered, ec2types.Tag{Key: aws.String(key), Value: tag.Value})
+	}
+	return filtered
+}
+
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
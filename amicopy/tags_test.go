@@ -0,0 +1,86 @@
+package amicopy
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestTagFilter_Apply(t *testing.T) {
+	tags := TagsFromMap(map[string]string{
+		"Name":             "base",
+		"CostCentre":       "1234",
+		"packer:builder":   "i-0123456789abcdef0",
+		"packer:os":        "linux",
+		"build:source_ami": "ami-12345678",
+	})
+
+	for _, tc := range []struct {
+		name     string
+		filter   *TagFilter
+		expected map[string]string
+	}{
+		{
+			name:   "nil",
+			filter: nil,
+			expected: map[string]string{
+				"Name":             "base",
+				"CostCentre":       "1234",
+				"packer:builder":   "i-0123456789abcdef0",
+				"packer:os":        "linux",
+				"build:source_ami": "ami-12345678",
+			},
+		},
+		{
+			name: "include",
+			filter: &TagFilter{
+				Include: []*regexp.Regexp{regexp.MustCompile(`^packer:`), regexp.MustCompile(`^Name$`)},
+			},
+			expected: map[string]string{
+				"Name":           "base",
+				"packer:builder": "i-0123456789abcdef0",
+				"packer:os":      "linux",
+			},
+		},
+		{
+			name: "include and exclude",
+			filter: &TagFilter{
+				Include: []*regexp.Regexp{regexp.MustCompile(`^packer:`)},
+				Exclude: []*regexp.Regexp{regexp.MustCompile(`builder`)},
+			},
+			expected: map[string]string{
+				"packer:os": "linux",
+			},
+		},
+		{
+			name: "rename",
+			filter: &TagFilter{
+				Exclude: []*regexp.Regexp{regexp.MustCompile(`^CostCentre$`)},
+				Renames: []TagRename{
+					{Pattern: regexp.MustCompile(`^packer:(.*)$`), Replacement: "image:$1"},
+					{Pattern: regexp.MustCompile(`^(packer|build):`), Replacement: "unused:"},
+				},
+			},
+			expected: map[string]string{
+				"Name":              "base",
+				"image:builder":     "i-0123456789abcdef0",
+				"image:os":          "linux",
+				"unused:source_ami": "ami-12345678",
+			},
+		},
+	} {
+		if actual := tagMap(tc.filter.Apply(tags)); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func tagMap(tags []ec2types.Tag) map[string]string {
+	m := map[string]string{}
+	for _, tag := range tags {
+		m[*tag.Key] = *tag.Value
+	}
+	return m
+}
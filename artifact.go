@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
+
+	"github.com/martinbaillie/packer-plugin-ami-copy/amicopy"
+)
+
+// ArtifactStateAMIs is the artifact state name of the copied AMIs, keyed by
+// account ID and then region.
+const ArtifactStateAMIs = "amis"
+
+// Artifact is an artifact implementation that contains the copied AMIs.
+type Artifact struct {
+	// Copies are the successful copies.
+	Copies []amicopy.AmiCopy
+
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	// We have no files
+	return nil
+}
+
+// Id returns every copy as `account:region:ami`, comma separated.
+func (a *Artifact) Id() string {
+	parts := make([]string, 0, len(a.Copies))
+	for _, c := range a.Copies {
+		parts = append(parts, fmt.Sprintf("%s:%s:%s",
+			c.TargetAccountID(), c.TargetRegion(), *c.Output().ImageId))
+	}
+
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (a *Artifact) String() string {
+	amiStrings := make([]string, 0, len(a.Copies))
+	for _, c := range a.Copies {
+		amiStrings = append(amiStrings, fmt.Sprintf("%s: %s: %s",
+			c.TargetAccountID(), c.TargetRegion(), *c.Output().ImageId))
+	}
+
+	sort.Strings(amiStrings)
+	return fmt.Sprintf("AMIs were copied:\n%s\n", strings.Join(amiStrings, "\n"))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	if _, ok := a.StateData[name]; ok {
+		return a.StateData[name]
+	}
+
+	switch name {
+	case ArtifactStateAMIs:
+		return a.stateAMIs()
+		// To be able to push metadata to HCP Packer Registry, Packer will read the 'par.artifact.metadata'
+		// state from artifacts to get a build's metadata.
+	case registryimage.ArtifactStateURI:
+		return a.stateHCPPackerRegistryMetadata()
+	default:
+		return nil
+	}
+}
+
+// Destroy deregisters the copied AMIs and deletes their snapshots. Existing and
+// resumed copies were not created by this run, so are left in place.
+func (a *Artifact) Destroy() error {
+	errors := make([]error, 0)
+
+	for _, c := range a.Copies {
+		if !c.Created() {
+			continue
+		}
+		log.Printf("Deregistering image ID (%s) from account (%s) in region (%s)",
+			*c.Output().ImageId, c.TargetAccountID(), c.TargetRegion())
+
+		if err := c.Deregister(context.Background()); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if len(errors) > 0 {
+		if len(errors) == 1 {
+			return errors[0]
+		} else {
+			return &packer.MultiError{Errors: errors}
+		}
+	}
+
+	return nil
+}
+
+// stateAMIs returns the copied AMI IDs keyed by account ID and then region.
+func (a *Artifact) stateAMIs() map[string]map[string]string {
+	amis := make(map[string]map[string]string)
+	for _, c := range a.Copies {
+		if _, ok := amis[c.TargetAccountID()]; !ok {
+			amis[c.TargetAccountID()] = make(map[string]string)
+		}
+		amis[c.TargetAccountID()][c.TargetRegion()] = *c.Output().ImageId
+	}
+	return amis
+}
+
+// stateHCPPackerRegistryMetadata will write the metadata as an hcpRegistryImage
+// for each of the copied AMIs present in this artifact.
+func (a *Artifact) stateHCPPackerRegistryMetadata() interface{} {
+	images := make([]*registryimage.Image, 0, len(a.Copies))
+	for _, c := range a.Copies {
+		input := c.Input()
+		images = append(images, &registryimage.Image{
+			ImageID:        *c.Output().ImageId,
+			ProviderName:   "aws",
+			ProviderRegion: c.TargetRegion(),
+			SourceImageID:  *input.SourceImageId,
+			Labels: map[string]string{
+				"account_id":    c.TargetAccountID(),
+				"region":        c.TargetRegion(),
+				"source_ami":    *input.SourceImageId,
+				"source_region": *input.SourceRegion,
+			},
+		})
+	}
+	return images
+}
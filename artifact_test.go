@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
+
+	"github.com/martinbaillie/packer-plugin-ami-copy/amicopy"
+)
+
+// stubCopy is an amicopy.AmiCopy that has already been copied.
+type stubCopy struct {
+	amicopy.AmiCopy
+	account, region, imageID string
+}
+
+func (c *stubCopy) TargetAccountID() string { return c.account }
+func (c *stubCopy) TargetRegion() string    { return c.region }
+func (c *stubCopy) Input() *ec2.CopyImageInput {
+	return &ec2.CopyImageInput{SourceImageId: aws.String("ami-0"), SourceRegion: aws.String("eu-west-1")}
+}
+func (c *stubCopy) Output() *ec2.CopyImageOutput {
+	return &ec2.CopyImageOutput{ImageId: aws.String(c.imageID)}
+}
+
+func testArtifact() *Artifact {
+	return &Artifact{
+		Copies: []amicopy.AmiCopy{
+			&stubCopy{account: "456789012345", region: "eu-west-1", imageID: "ami-3"},
+			&stubCopy{account: "123456789012", region: "us-east-1", imageID: "ami-2"},
+			&stubCopy{account: "123456789012", region: "eu-west-1", imageID: "ami-1"},
+		},
+	}
+}
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestArtifact_Id(t *testing.T) {
+	expected := "123456789012:eu-west-1:ami-1,123456789012:us-east-1:ami-2,456789012345:eu-west-1:ami-3"
+	if id := testArtifact().Id(); id != expected {
+		t.Fatalf("bad: %s", id)
+	}
+}
+
+func TestArtifact_StateAMIs(t *testing.T) {
+	expected := map[string]map[string]string{
+		"123456789012": {"eu-west-1": "ami-1", "us-east-1": "ami-2"},
+		"456789012345": {"eu-west-1": "ami-3"},
+	}
+	if amis := testArtifact().State(ArtifactStateAMIs); !reflect.DeepEqual(amis, expected) {
+		t.Fatalf("bad: %#v", amis)
+	}
+}
+
+func TestArtifact_StateHCPPackerRegistryMetadata(t *testing.T) {
+	images, ok := testArtifact().State(registryimage.ArtifactStateURI).([]*registryimage.Image)
+	if !ok || len(images) != 3 {
+		t.Fatalf("bad: %#v", images)
+	}
+
+	expected := &registryimage.Image{
+		ImageID:        "ami-3",
+		ProviderName:   "aws",
+		ProviderRegion: "eu-west-1",
+		SourceImageID:  "ami-0",
+		Labels: map[string]string{
+			"account_id":    "456789012345",
+			"region":        "eu-west-1",
+			"source_ami":    "ami-0",
+			"source_region": "eu-west-1",
+		},
+	}
+	if !reflect.DeepEqual(images[0], expected) {
+		t.Fatalf("bad: %#v", images[0])
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// copyLimits bounds the copies in flight to each account, and to each region
+// of each account, as AWS limits the concurrent copies to an account and
+// region.
+type copyLimits struct {
+	perAccount int
+	perRegion  int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// acquire waits for a slot for a copy to the account and region, returning
+// the function to release it.
+func (l *copyLimits) acquire(ctx context.Context, accountID, region string) (func(), error) {
+	var acquired []chan struct{}
+	release := func() {
+		for _, slots := range acquired {
+			<-slots
+		}
+	}
+
+	for _, slots := range []chan struct{}{
+		l.slotsFor(accountID, l.perAccount),
+		l.slotsFor(accountID+"/"+region, l.perRegion),
+	} {
+		if slots == nil {
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+			acquired = append(acquired, slots)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// slotsFor returns the slots for the key, or nil if it is unlimited.
+func (l *copyLimits) slotsFor(key string, limit int) chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.slots == nil {
+		l.slots = map[string]chan struct{}{}
+	}
+	if _, ok := l.slots[key]; !ok {
+		l.slots[key] = make(chan struct{}, limit)
+	}
+	return l.slots[key]
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCopyLimits(t *testing.T) {
+	limits := &copyLimits{perAccount: 2, perRegion: 1}
+	tryAcquire := func(accountID, region string) (func(), error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		return limits.acquire(ctx, accountID, region)
+	}
+
+	releaseWest, err := tryAcquire("123456789012", "eu-west-1")
+	if err != nil {
+		t.Fatalf("expected a slot: %s", err)
+	}
+	if _, err = tryAcquire("123456789012", "eu-west-1"); err == nil {
+		t.Fatal("expected the region to be at its limit")
+	}
+	if _, err = tryAcquire("123456789012", "us-east-1"); err != nil {
+		t.Fatalf("expected a slot in another region: %s", err)
+	}
+	if _, err = tryAcquire("123456789012", "eu-central-1"); err == nil {
+		t.Fatal("expected the account to be at its limit")
+	}
+	if _, err = tryAcquire("210987654321", "eu-west-1"); err != nil {
+		t.Fatalf("expected a slot in another account: %s", err)
+	}
+
+	releaseWest()
+	if _, err = tryAcquire("123456789012", "eu-central-1"); err != nil {
+		t.Fatalf("expected a slot once released: %s", err)
+	}
+}
+
+func TestCopyLimits_unlimited(t *testing.T) {
+	limits := &copyLimits{}
+	for i := 0; i < 10; i++ {
+		if _, err := limits.acquire(context.Background(), "123456789012", "eu-west-1"); err != nil {
+			t.Fatalf("expected no limit: %s", err)
+		}
+	}
+}
+
+func TestRequeueBackoff(t *testing.T) {
+	for attempt, expected := range map[int]time.Duration{
+		1:  15 * time.Second,
+		2:  30 * time.Second,
+		4:  2 * time.Minute,
+		9:  5 * time.Minute,
+		10: 5 * time.Minute,
+	} {
+		if actual := requeueBackoff(attempt); actual != expected {
+			t.Errorf("attempt %d: expected %s, got %s", attempt, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/flock"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/martinbaillie/packer-plugin-ami-copy/amicopy"
+)
+
+// outputManifests writes the state of every copy to `manifest_output`, if set.
+func (p *PostProcessor) outputManifests(ui packer.Ui, copies []amicopy.AmiCopy) {
+	if p.config.ManifestOutput == "" {
+		return
+	}
+
+	manifests := []*amicopy.AmiManifest{}
+	for _, c := range copies {
+		manifests = append(manifests, c.Manifest())
+	}
+	if err := writeManifests(
+		p.config.ManifestOutput,
+		p.config.ManifestFormat,
+		p.config.ManifestPretty,
+		p.config.ManifestMode == manifestModeAppend,
+		manifests,
+	); err != nil {
+		ui.Say(fmt.Sprintf("Unable to write out manifest to %s: %s", p.config.ManifestOutput, err))
+	}
+}
+
+// writeManifests atomically writes the manifests to the output. When appending,
+// an advisory lock is held while they are merged with the existing manifest so
+// that concurrent builds sharing the output do not lose each other's copies.
+func writeManifests(output, format string, pretty, appendMode bool, manifests []*amicopy.AmiManifest) error {
+	if appendMode {
+		lock := flock.New(output + ".lock")
+		if err := lock.Lock(); err != nil {
+			return fmt.Errorf("Unable to lock manifest: %s", err)
+		}
+		defer lock.Unlock()
+
+		existing, err := readManifests(output)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		manifests = amicopy.MergeManifests(existing, manifests)
+	}
+
+	rawManifest, err := amicopy.NewManifest(manifests).Encode(format, pretty)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(output, rawManifest, 0644)
+}
+
+func readManifests(input string) ([]*amicopy.AmiManifest, error) {
+	rawManifest, err := ioutil.ReadFile(input)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := amicopy.ParseManifest(rawManifest)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Copies, nil
+}
+
+// writeFileAtomic writes the data to a temporary file alongside the output
+// before renaming it into place, so readers never see a partial write.
+func writeFileAtomic(output string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(output), filepath.Base(output)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), output)
+}
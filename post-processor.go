@@ -1,24 +1,29 @@
-//go:generate mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,Target,TagRename
 
 package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/mitchellh/mapstructure"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 
 	"github.com/hashicorp/packer-plugin-amazon/builder/chroot"
@@ -41,6 +46,29 @@ import (
 // nolint: golint
 const BuilderId = "packer.post-processor.ami-copy"
 
+// Actions to take on the successful copies when any copy fails.
+const (
+	onFailureKeep     = "keep"
+	onFailureRollback = "rollback"
+)
+
+// Modes of writing the manifest when it already exists.
+const (
+	manifestModeOverwrite = "overwrite"
+	manifestModeAppend    = "append"
+)
+
+// maxCopyAttempts is how many times a copy is attempted when it hits AWS quotas
+// or throttling.
+const maxCopyAttempts = 10
+
+// rollbackTimeout bounds rolling back the copies, which carries on after the
+// run is cancelled.
+const rollbackTimeout = 10 * time.Minute
+
+// defaultProvenanceTagPrefix is the prefix of the provenance tag keys.
+const defaultProvenanceTagPrefix = "ami-copy:"
+
 // Config is the post-processor configuration with interpolation supported.
 // See https://www.packer.io/docs/builders/amazon.html for details.
 type Config struct {
@@ -49,14 +77,94 @@ type Config struct {
 	awscommon.AMIConfig    `mapstructure:",squash"`
 
 	// Variables specific to this post-processor
-	RoleName        string `mapstructure:"role_name"`
-	CopyConcurrency int    `mapstructure:"copy_concurrency"`
-	EnsureAvailable bool   `mapstructure:"ensure_available"`
-	KeepArtifact    string `mapstructure:"keep_artifact"`
-	ManifestOutput  string `mapstructure:"manifest_output"`
-	TagsOnly        bool   `mapstructure:"tags_only"`
-
-	ctx interpolate.Context
+	RoleName                    string        `mapstructure:"role_name"`
+	CopyConcurrency             int           `mapstructure:"copy_concurrency"`
+	MaxConcurrentPerAccount     int           `mapstructure:"max_concurrent_per_account"`
+	MaxConcurrentPerRegion      int           `mapstructure:"max_concurrent_per_region"`
+	DestinationRegions          []string      `mapstructure:"destination_regions"`
+	EnsureAvailable             bool          `mapstructure:"ensure_available"`
+	EnsureAvailableTimeout      time.Duration `mapstructure:"ensure_available_timeout"`
+	EnsureAvailablePollInterval time.Duration `mapstructure:"ensure_available_poll_interval"`
+	KeepArtifact                string        `mapstructure:"keep_artifact"`
+	ManifestOutput              string        `mapstructure:"manifest_output"`
+	ManifestFormat              string        `mapstructure:"manifest_format"`
+	ManifestPretty              bool          `mapstructure:"manifest_pretty"`
+	ManifestMode                string        `mapstructure:"manifest_mode"`
+	OnFailure                   string        `mapstructure:"on_failure"`
+	ResumeFromManifest          string        `mapstructure:"resume_from_manifest"`
+	SkipExisting                bool          `mapstructure:"skip_existing"`
+	TagsOnly                    bool          `mapstructure:"tags_only"`
+	Targets                     []Target      `mapstructure:"target"`
+
+	// Publishing of copied image IDs to SSM Parameter Store
+	SSMParameterName      string            `mapstructure:"ssm_parameter_name"`
+	SSMParameterOverwrite config.Trilean    `mapstructure:"ssm_parameter_overwrite"`
+	SSMParameterLabels    []string          `mapstructure:"ssm_parameter_labels"`
+	SSMParameterTags      map[string]string `mapstructure:"ssm_parameter_tags"`
+
+	// Updating of launch templates to use the copied images
+	LaunchTemplateNames      []string          `mapstructure:"launch_template_names"`
+	LaunchTemplateTags       map[string]string `mapstructure:"launch_template_tags"`
+	LaunchTemplateSetDefault bool              `mapstructure:"launch_template_set_default"`
+
+	// Pruning of older images in the same lineage as the copied images
+	RetainCount       int    `mapstructure:"retain_count"`
+	RetainDays        int    `mapstructure:"retain_days"`
+	LineageNamePrefix string `mapstructure:"lineage_name_prefix"`
+	LineageTag        string `mapstructure:"lineage_tag"`
+	ProtectInUse      bool   `mapstructure:"protect_in_use"`
+
+	// Deprecation of older images in the same lineage as the copied images
+	DeprecatePreviousAfter time.Duration `mapstructure:"deprecate_previous_after"`
+	DisablePrevious        bool          `mapstructure:"disable_previous"`
+
+	// Filtering and renaming of the source image tags copied to the targets
+	TagInclude []string    `mapstructure:"tag_include"`
+	TagExclude []string    `mapstructure:"tag_exclude"`
+	TagRenames []TagRename `mapstructure:"tag_rename"`
+
+	// Provenance tags recording where each copy came from
+	AddProvenanceTags   bool   `mapstructure:"add_provenance_tags"`
+	ProvenanceTagPrefix string `mapstructure:"provenance_tag_prefix"`
+
+	// Retrying AWS API calls, along with `max_retries`
+	RetryMinBackoff     time.Duration `mapstructure:"retry_min_backoff"`
+	RetryMaxBackoff     time.Duration `mapstructure:"retry_max_backoff"`
+	RetryableErrorCodes []string      `mapstructure:"retryable_error_codes"`
+	RetryMode           string        `mapstructure:"retry_mode"`
+
+	ctx       interpolate.Context
+	tagFilter *amicopy.TagFilter
+}
+
+// Target is an account to copy images to, along with the settings specific to
+// that account. Unset settings fall back to their post-processor wide
+// equivalents.
+type Target struct {
+	AccountID   string            `mapstructure:"account_id" required:"true"`
+	Regions     []string          `mapstructure:"regions"`
+	RoleArn     string            `mapstructure:"role_arn"`
+	KmsKeyId    string            `mapstructure:"kms_key_id"`
+	EncryptBoot config.Trilean    `mapstructure:"encrypt_boot"`
+	Tags        map[string]string `mapstructure:"tags"`
+	TagsOnly    config.Trilean    `mapstructure:"tags_only"`
+}
+
+// TagRename is a rule renaming source image tag keys matching a regular
+// expression.
+type TagRename struct {
+	Pattern     string `mapstructure:"pattern" required:"true"`
+	Replacement string `mapstructure:"replacement"`
+}
+
+// copyTemplateData is the data available when interpolating per copy settings.
+type copyTemplateData struct {
+	BuildName     string
+	TargetAccount string
+	Region        string
+	SourceAMI     string
+	SourceAMIName string
+	SourceRegion  string
 }
 
 // PostProcessor implements Packer's PostProcessor interface.
@@ -72,32 +180,270 @@ func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec {
 func (p *PostProcessor) Configure(raws ...interface{}) error {
 	p.config.ctx.Funcs = awscommon.TemplateFuncs
 
+	var md mapstructure.Metadata
 	if err := config.Decode(&p.config, &config.DecodeOpts{
+		Metadata:           &md,
 		PluginType:         BuilderId,
 		Interpolate:        true,
 		InterpolateContext: &p.config.ctx,
 		InterpolateFilter: &interpolate.RenderFilter{
-			Exclude: []string{},
+			Exclude: []string{
+				"ami_description",
+				"ami_name",
+				"lineage_name_prefix",
+				"snapshot_tag",
+				"snapshot_tags",
+				"ssm_parameter_name",
+				"tag",
+				"tags",
+				"target",
+			},
 		},
 	}, raws...); err != nil {
 		return err
 	}
 
-	if len(p.config.AMIUsers) == 0 {
-		return errors.New("ami_users must be set")
+	// The `tag` and `snapshot_tag` blocks are the HCL2 form of `tags` and
+	// `snapshot_tags`.
+	if errs := p.config.AMITag.CopyOn(&p.config.AMITags); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if errs := p.config.SnapshotTag.CopyOn(&p.config.SnapshotTags); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// `ami_users` is shorthand for a target per account using only the
+	// post-processor wide settings.
+	for _, user := range p.config.AMIUsers {
+		p.config.Targets = append(p.config.Targets, Target{AccountID: user})
+	}
+
+	if len(p.config.Targets) == 0 {
+		return errors.New("ami_users or target must be set")
+	}
+
+	for i := range p.config.Targets {
+		target := &p.config.Targets[i]
+
+		// Targets are excluded from interpolation so that their tags can be
+		// rendered per copy, the rest is rendered here.
+		for _, field := range append([]*string{&target.AccountID, &target.RoleArn, &target.KmsKeyId},
+			stringPointers(target.Regions)...) {
+			var err error
+			if *field, err = interpolate.Render(*field, &p.config.ctx); err != nil {
+				return fmt.Errorf("target: %s", err)
+			}
+		}
+
+		if target.AccountID == "" {
+			return errors.New("target account_id must be set")
+		}
+		if len(target.Regions) == 0 {
+			target.Regions = p.config.DestinationRegions
+		}
+		if target.RoleArn == "" && p.config.RoleName != "" {
+			target.RoleArn = fmt.Sprintf("arn:aws:iam::%s:role/%s", target.AccountID, p.config.RoleName)
+		}
+		if target.EncryptBoot == config.TriUnset {
+			target.EncryptBoot = p.config.AMIEncryptBootVolume
+		}
+		if target.TagsOnly == config.TriUnset {
+			target.TagsOnly = config.TrileanFromBool(p.config.TagsOnly)
+		}
+
+		if target.TagsOnly.True() && len(target.Regions) > 0 {
+			return fmt.Errorf("target %s: tags_only cannot be used with regions", target.AccountID)
+		}
 	}
 
 	if len(p.config.KeepArtifact) == 0 {
 		p.config.KeepArtifact = "true"
 	}
 
+	switch p.config.OnFailure {
+	case "":
+		p.config.OnFailure = onFailureKeep
+	case onFailureKeep, onFailureRollback:
+	default:
+		return fmt.Errorf("on_failure must be one of %q or %q", onFailureKeep, onFailureRollback)
+	}
+
+	if p.config.ManifestFormat == "" {
+		p.config.ManifestFormat = amicopy.ManifestFormatJSON
+	}
+	var validFormat bool
+	for _, format := range amicopy.ManifestFormats {
+		validFormat = validFormat || p.config.ManifestFormat == format
+	}
+	if !validFormat {
+		return fmt.Errorf("manifest_format must be one of %s",
+			strings.Join(amicopy.ManifestFormats, ", "))
+	}
+
+	switch p.config.ManifestMode {
+	case "":
+		p.config.ManifestMode = manifestModeOverwrite
+	case manifestModeOverwrite:
+	case manifestModeAppend:
+		if p.config.ManifestFormat != amicopy.ManifestFormatJSON &&
+			p.config.ManifestFormat != amicopy.ManifestFormatYAML {
+			return fmt.Errorf("manifest_mode %q requires a manifest_format of %q or %q",
+				manifestModeAppend, amicopy.ManifestFormatJSON, amicopy.ManifestFormatYAML)
+		}
+	default:
+		return fmt.Errorf("manifest_mode must be one of %q or %q", manifestModeOverwrite, manifestModeAppend)
+	}
+
+	if p.config.CopyConcurrency < 0 || p.config.MaxConcurrentPerAccount < 0 || p.config.MaxConcurrentPerRegion < 0 {
+		return errors.New("copy_concurrency, max_concurrent_per_account and max_concurrent_per_region cannot be negative")
+	}
+	if (p.config.MaxConcurrentPerAccount > 0 || p.config.MaxConcurrentPerRegion > 0) && !p.config.EnsureAvailable {
+		// Without waiting, a copy would give up its place as soon as it is
+		// submitted, and nothing would be limited.
+		return errors.New("ensure_available must be set to limit max_concurrent_per_account or max_concurrent_per_region")
+	}
+
+	// An explicit `max_retries` of 0 disables retries, so the default is only
+	// applied when it is unset.
+	if !slices.Contains(md.Keys, "max_retries") {
+		p.config.MaxRetries = amicopy.DefaultMaxRetries
+	}
+	if p.config.RetryMinBackoff == 0 {
+		p.config.RetryMinBackoff = amicopy.DefaultMinBackoff
+	}
+	if p.config.RetryMaxBackoff == 0 {
+		p.config.RetryMaxBackoff = amicopy.DefaultMaxBackoff
+	}
+	if p.config.RetryableErrorCodes == nil {
+		p.config.RetryableErrorCodes = amicopy.DefaultRetryableErrorCodes
+	}
+	if p.config.MaxRetries < 0 || p.config.RetryMinBackoff < 0 || p.config.RetryMinBackoff > p.config.RetryMaxBackoff {
+		return errors.New("max_retries cannot be negative, and retry_min_backoff must be between 0 and retry_max_backoff")
+	}
+	switch p.config.RetryMode {
+	case "":
+		p.config.RetryMode = amicopy.RetryModeStandard
+	case amicopy.RetryModeStandard, amicopy.RetryModeAdaptive:
+	default:
+		return fmt.Errorf("retry_mode must be either %s or %s",
+			amicopy.RetryModeStandard, amicopy.RetryModeAdaptive)
+	}
+
+	if p.config.EnsureAvailableTimeout == 0 {
+		p.config.EnsureAvailableTimeout = amicopy.DefaultAvailableTimeout
+	}
+	if p.config.EnsureAvailablePollInterval == 0 {
+		p.config.EnsureAvailablePollInterval = amicopy.DefaultPollInterval
+	}
+	if p.config.EnsureAvailableTimeout < 0 || p.config.EnsureAvailablePollInterval < 0 {
+		return errors.New("ensure_available_timeout and ensure_available_poll_interval cannot be negative")
+	}
+
+	if p.config.ProvenanceTagPrefix == "" {
+		p.config.ProvenanceTagPrefix = defaultProvenanceTagPrefix
+	}
+	// Provenance tags give copies a lineage by build name when none is set.
+	if p.config.AddProvenanceTags && p.config.LineageNamePrefix == "" && p.config.LineageTag == "" {
+		p.config.LineageTag = p.config.ProvenanceTagPrefix + amicopy.ProvenanceTagBuildName
+	}
+
+	if p.config.RetainCount < 0 || p.config.RetainDays < 0 {
+		return errors.New("retain_count and retain_days cannot be negative")
+	}
+	if p.config.RetainCount > 0 || p.config.RetainDays > 0 {
+		if p.config.LineageNamePrefix == "" && p.config.LineageTag == "" {
+			return errors.New("lineage_name_prefix or lineage_tag must be set to prune older images")
+		}
+		if !p.config.EnsureAvailable {
+			return errors.New("ensure_available must be set to prune older images")
+		}
+	}
+
+	if p.config.DeprecatePreviousAfter < 0 {
+		return errors.New("deprecate_previous_after cannot be negative")
+	}
+	if p.config.DeprecatePreviousAfter > 0 || p.config.DisablePrevious {
+		if p.config.LineageNamePrefix == "" && p.config.LineageTag == "" {
+			return errors.New("lineage_name_prefix or lineage_tag must be set to deprecate older images")
+		}
+		if !p.config.EnsureAvailable {
+			return errors.New("ensure_available must be set to deprecate older images")
+		}
+	}
+
+	if p.config.SSMParameterName != "" && !p.config.EnsureAvailable {
+		return errors.New("ensure_available must be set to publish to ssm_parameter_name")
+	}
+	if (len(p.config.LaunchTemplateNames) > 0 || len(p.config.LaunchTemplateTags) > 0) && !p.config.EnsureAvailable {
+		return errors.New("ensure_available must be set to update launch templates")
+	}
+
+	if p.config.DeprecationTime != "" {
+		if _, err := time.Parse(time.RFC3339, p.config.DeprecationTime); err != nil {
+			return fmt.Errorf("deprecate_at is not a valid time: %q. Expect time format: YYYY-MM-DDTHH:MM:SSZ",
+				p.config.DeprecationTime)
+		}
+	}
+
+	if len(p.config.TagInclude) > 0 || len(p.config.TagExclude) > 0 || len(p.config.TagRenames) > 0 {
+		p.config.tagFilter = &amicopy.TagFilter{}
+		for _, pattern := range p.config.TagInclude {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("tag_include %q is not a valid regular expression: %s", pattern, err)
+			}
+			p.config.tagFilter.Include = append(p.config.tagFilter.Include, re)
+		}
+		for _, pattern := range p.config.TagExclude {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("tag_exclude %q is not a valid regular expression: %s", pattern, err)
+			}
+			p.config.tagFilter.Exclude = append(p.config.tagFilter.Exclude, re)
+		}
+		for _, rename := range p.config.TagRenames {
+			re, err := regexp.Compile(rename.Pattern)
+			if err != nil {
+				return fmt.Errorf("tag_rename pattern %q is not a valid regular expression: %s", rename.Pattern, err)
+			}
+			p.config.tagFilter.Renames = append(p.config.tagFilter.Renames, amicopy.TagRename{
+				Pattern:     re,
+				Replacement: rename.Replacement,
+			})
+		}
+	}
+
+	if keepArtifact, err := strconv.ParseBool(p.config.KeepArtifact); err != nil {
+		return fmt.Errorf("keep_artifact must be a boolean: %s", err)
+	} else if !keepArtifact {
+		if !p.config.EnsureAvailable {
+			return errors.New("ensure_available must be set when keep_artifact is false " +
+				"so that the source AMIs are not removed while they are still being copied")
+		}
+		for _, target := range p.config.Targets {
+			if target.TagsOnly.True() {
+				return errors.New("keep_artifact cannot be false when tags_only is used " +
+					"as the target accounts use the source AMI")
+			}
+		}
+	}
+
 	return nil
 }
 
 // PostProcess will copy the source AMI to each of the target accounts as
-// designated by the mandatory `ami_users` variable. It will optionally
-// encrypt the copied AMIs (`encrypt_boot`) with `kms_key_id` if set, or the
-// default EBS KMS key if unset. Tags will be copied with the image.
+// designated by the `target` blocks and `ami_users` variable. It will
+// optionally encrypt the copied AMIs (`encrypt_boot`) with `kms_key_id` if
+// set, or the default EBS KMS key if unset. Tags will be copied with the
+// image, along with any target specific tags.
+//
+// Each image is copied to every region of the target, or to the region it was
+// built in if unset. A target's own `kms_key_id` takes precedence over a
+// region specific key from `region_kms_key_ids`, which in turn takes
+// precedence over the post-processor wide `kms_key_id`.
+//
+// On success an Artifact of the copies is returned in place of the source
+// artifact, so that later post-processors operate on the copied AMIs.
 //
 // Copies are executed concurrently. This concurrency is unlimited unless
 // controller by `copy_concurrency`.
@@ -128,91 +474,341 @@ func (p *PostProcessor) PostProcess(
 	if err != nil {
 		return artifact, keepArtifactBool, false, err
 	}
+	retryPolicy := &amicopy.RetryPolicy{
+		MaxRetries:          p.config.MaxRetries,
+		MinBackoff:          p.config.RetryMinBackoff,
+		MaxBackoff:          p.config.RetryMaxBackoff,
+		RetryableErrorCodes: p.config.RetryableErrorCodes,
+		Mode:                p.config.RetryMode,
+	}
+	awscfg.Retryer = retryPolicy.Retryer
 
 	// Copy futures
 	var (
-		amis   = amisFromArtifactID(artifact.Id())
-		users  = p.config.AMIUsers
-		copies []amicopy.AmiCopy
+		amis    = amisFromArtifactID(artifact.Id())
+		sources = make([]*ec2types.Image, len(amis))
+		copies  []amicopy.AmiCopy
+		pollers = &amicopy.Pollers{Interval: p.config.EnsureAvailablePollInterval, Ui: ui}
+
+		targetClients = map[string]*regionClients{}
 	)
-	for _, ami := range amis {
+	for i, ami := range amis {
+		ami.conn = ec2.NewFromConfig(p.regionConfig(awscfg, "", ami.region))
+
 		var source *ec2types.Image
-		if source, err = amicopy.LocateSingleAMI(
-			ctx,
-			ami.id,
-			ec2.NewFromConfig(*awscfg, func(o *ec2.Options) {
-				o.Region = ami.region
-			}),
-		); err != nil || source == nil {
+		if source, err = amicopy.LocateSingleAMI(ctx, ami.id, ami.conn); err != nil || source == nil {
 			return artifact, keepArtifactBool, false, err
 		}
+		sources[i] = source
 
-		for _, user := range users {
-			var conn *ec2.Client
-			{
-				if p.config.RoleName != "" {
-					var (
-						role = fmt.Sprintf("arn:aws:iam::%s:role/%s", user, p.config.RoleName)
-						stsc = sts.NewFromConfig(awscfg.Copy())
-					)
-					conn = ec2.NewFromConfig(awscfg.Copy(), func(o *ec2.Options) {
-						o.Credentials = stscreds.NewAssumeRoleProvider(stsc, role)
-						o.Region = ami.region
-					})
-				} else {
-					conn = ec2.NewFromConfig(*awscfg, func(o *ec2.Options) {
-						o.Region = ami.region
-					})
-				}
+		var name, description string
+		{
+			if source.Name != nil {
+				name = *source.Name
+			}
+			if source.Description != nil {
+				description = *source.Description
+			}
+		}
+
+		for _, target := range p.config.Targets {
+			regions := target.Regions
+			if len(regions) == 0 {
+				regions = []string{ami.region}
 			}
 
-			var name, description string
-			{
-				if source.Name != nil {
-					name = *source.Name
+			for _, region := range regions {
+				kmsKeyID := target.KmsKeyId
+				if kmsKeyID == "" {
+					kmsKeyID = p.config.AMIKmsKeyId
+					if regionKeyID, ok := p.config.AMIRegionKMSKeyIDs[region]; ok {
+						kmsKeyID = regionKeyID
+					}
 				}
-				if source.Description != nil {
-					description = *source.Description
+
+				p.config.ctx.Data = &copyTemplateData{
+					BuildName:     p.config.PackerBuildName,
+					TargetAccount: target.AccountID,
+					Region:        region,
+					SourceAMI:     ami.id,
+					SourceAMIName: name,
+					SourceRegion:  ami.region,
+				}
+
+				copyName, copyDescription := name, description
+				if p.config.AMIName != "" {
+					if copyName, err = interpolate.Render(p.config.AMIName, &p.config.ctx); err != nil {
+						return artifact, keepArtifactBool, false,
+							fmt.Errorf("Unable to render ami_name: %s", err)
+					}
+				}
+				if p.config.AMIDescription != "" {
+					if copyDescription, err = interpolate.Render(p.config.AMIDescription, &p.config.ctx); err != nil {
+						return artifact, keepArtifactBool, false,
+							fmt.Errorf("Unable to render ami_description: %s", err)
+					}
+				}
+
+				tags, err := p.renderTags(p.config.AMITags)
+				if err != nil {
+					return artifact, keepArtifactBool, false,
+						fmt.Errorf("Unable to render tags: %s", err)
+				}
+				targetTags, err := p.renderTags(target.Tags)
+				if err != nil {
+					return artifact, keepArtifactBool, false,
+						fmt.Errorf("Unable to render target tags: %s", err)
+				}
+				for k, v := range targetTags {
+					tags[k] = v
+				}
+				snapshotTags, err := p.renderTags(p.config.SnapshotTags)
+				if err != nil {
+					return artifact, keepArtifactBool, false,
+						fmt.Errorf("Unable to render snapshot_tags: %s", err)
+				}
+
+				// Copies to the same account and region share their clients,
+				// and so their retryer and any adaptive rate limiting.
+				clientsKey := target.AccountID + "/" + region + "/" + target.RoleArn
+				clients, ok := targetClients[clientsKey]
+				if !ok {
+					cfg := p.regionConfig(awscfg, target.RoleArn, region)
+					clients = &regionClients{ec2: ec2.NewFromConfig(cfg), ssm: ssm.NewFromConfig(cfg)}
+					targetClients[clientsKey] = clients
+				}
+				ec2Conn := clients.ec2
+				amiCopy := &amicopy.AmiCopyImpl{
+					EC2:              ec2Conn,
+					SourceImage:      source,
+					EnsureAvailable:  p.config.EnsureAvailable,
+					AvailableTimeout: p.config.EnsureAvailableTimeout,
+					PollInterval:     p.config.EnsureAvailablePollInterval,
+					Poller:           pollers.Get(target.AccountID, region, ec2Conn),
+					SkipExisting:     p.config.SkipExisting,
+					BuildName:        p.config.PackerBuildName,
+					TagsOnly:         target.TagsOnly.True(),
+					Tags:             amicopy.TagsFromMap(tags),
+					SnapshotTags:     amicopy.TagsFromMap(snapshotTags),
+					TagFilter:        p.config.tagFilter,
+					Provenance:       p.provenance(),
+				}
+				amiCopy.SetTargetAccountID(target.AccountID)
+				amiCopy.SetTargetRegion(region)
+
+				if p.config.SSMParameterName != "" {
+					parameterName, err := interpolate.Render(p.config.SSMParameterName, &p.config.ctx)
+					if err != nil {
+						return artifact, keepArtifactBool, false,
+							fmt.Errorf("Unable to render ssm_parameter_name: %s", err)
+					}
+					amiCopy.SSMParameter = &amicopy.SSMParameter{
+						SSM:       clients.ssm,
+						Name:      parameterName,
+						Overwrite: !p.config.SSMParameterOverwrite.False(),
+						Labels:    p.config.SSMParameterLabels,
+						Tags:      ssmTags(p.config.SSMParameterTags),
+					}
 				}
-			}
 
-			amiCopy := &amicopy.AmiCopyImpl{
-				EC2:             conn,
-				SourceImage:     source,
-				EnsureAvailable: p.config.EnsureAvailable,
-				TagsOnly:        p.config.TagsOnly,
+				if len(p.config.LaunchTemplateNames) > 0 || len(p.config.LaunchTemplateTags) > 0 {
+					amiCopy.LaunchTemplates = &amicopy.LaunchTemplates{
+						Names:      p.config.LaunchTemplateNames,
+						Tags:       p.config.LaunchTemplateTags,
+						SetDefault: p.config.LaunchTemplateSetDefault,
+					}
+				}
+
+				namePrefix, err := interpolate.Render(p.config.LineageNamePrefix, &p.config.ctx)
+				if err != nil {
+					return artifact, keepArtifactBool, false,
+						fmt.Errorf("Unable to render lineage_name_prefix: %s", err)
+				}
+				lineage := amicopy.Lineage{
+					NamePrefix: namePrefix,
+					TagKey:     p.config.LineageTag,
+				}
+
+				if p.config.RetainCount > 0 || p.config.RetainDays > 0 {
+					amiCopy.Retention = &amicopy.Retention{
+						Lineage:      lineage,
+						Count:        p.config.RetainCount,
+						Days:         p.config.RetainDays,
+						ProtectInUse: p.config.ProtectInUse,
+					}
+				}
+
+				if p.config.DeprecatePreviousAfter > 0 || p.config.DisablePrevious {
+					amiCopy.Deprecation = &amicopy.Deprecation{
+						Lineage:      lineage,
+						After:        p.config.DeprecatePreviousAfter,
+						Disable:      p.config.DisablePrevious,
+						ProtectInUse: p.config.ProtectInUse,
+					}
+				}
+
+				if p.config.DeprecationTime != "" {
+					// Validated by Configure.
+					amiCopy.DeprecateAt, _ = time.Parse(time.RFC3339, p.config.DeprecationTime)
+				}
+
+				amiCopy.SetInput(&ec2.CopyImageInput{
+					Name:          aws.String(copyName),
+					Description:   aws.String(copyDescription),
+					SourceImageId: aws.String(ami.id),
+					SourceRegion:  aws.String(ami.region),
+					KmsKeyId:      aws.String(kmsKeyID),
+					Encrypted:     aws.Bool(target.EncryptBoot.True()),
+				})
+
+				copies = append(copies, amiCopy)
 			}
-			amiCopy.SetTargetAccountID(user)
-			amiCopy.SetInput(&ec2.CopyImageInput{
-				Name:          aws.String(name),
-				Description:   aws.String(description),
-				SourceImageId: aws.String(ami.id),
-				SourceRegion:  aws.String(ami.region),
-				KmsKeyId:      aws.String(p.config.AMIKmsKeyId),
-				Encrypted:     aws.Bool(p.config.AMIEncryptBootVolume.True()),
-			})
+		}
+	}
 
-			copies = append(copies, amiCopy)
+	pending, resumed := copies, []amicopy.AmiCopy(nil)
+	if p.config.ResumeFromManifest != "" {
+		if pending, resumed, err = resumeAMIs(ctx, copies, ui, p.config.ResumeFromManifest); err != nil {
+			return artifact, keepArtifactBool, false, err
 		}
 	}
+	if p.config.EnsureAvailable {
+		pollers.Total = len(copies)
+	}
 
-	copyErrs := copyAMIs(ctx, copies, ui, p.config.ManifestOutput, p.config.CopyConcurrency)
+	copied, copyErrs := copyAMIs(ctx, pending, resumed, ui, p.config.CopyConcurrency, &copyLimits{
+		perAccount: p.config.MaxConcurrentPerAccount,
+		perRegion:  p.config.MaxConcurrentPerRegion,
+	})
 	if copyErrs > 0 {
+		if !keepArtifactBool {
+			ui.Say("Not removing the source AMIs as not all copies succeeded")
+		}
+
+		reconcile := "manual reconciliation may be required"
+		if p.config.OnFailure == onFailureRollback {
+			// Failed copies may also have created an image, such as when
+			// waiting for it timed out or publishing it failed.
+			if remaining := rollbackAMIs(ctx, copies, ui); len(remaining) == 0 {
+				reconcile = "copies have been rolled back"
+			}
+		}
+		p.outputManifests(ui, copies)
+
 		return artifact, true, false, fmt.Errorf(
-			"%d/%d AMI copies failed, manual reconciliation may be required", copyErrs, len(copies))
+			"%d/%d AMI copies failed, %s", copyErrs, len(copies), reconcile)
+	}
+	if finalizeErrs := finalizeAMIs(ctx, copied, ui); finalizeErrs > 0 {
+		if !keepArtifactBool {
+			ui.Say("Not removing the source AMIs as not all copies were published")
+		}
+		p.outputManifests(ui, copies)
+
+		// The copies themselves succeeded, and may already be published
+		// elsewhere, so they are kept whatever `on_failure` is.
+		return artifact, true, false, fmt.Errorf(
+			"%d/%d AMI copies could not be published, the copies have been kept", finalizeErrs, len(copied))
+	}
+	p.outputManifests(ui, copies)
+
+	if !keepArtifactBool {
+		for i, ami := range amis {
+			snapshotIDs, err := amicopy.DeregisterImage(ctx, ami.conn, sources[i])
+			if err != nil {
+				return artifact, true, false, fmt.Errorf(
+					"Unable to remove source AMI %s: %s", ami.id, err)
+			}
+			ui.Say(fmt.Sprintf("[%s] Removed source AMI %s and its snapshots: %s",
+				ami.region, ami.id, strings.Join(snapshotIDs, ", ")))
+		}
+		// The source AMIs are already gone so there is nothing left for
+		// Packer to destroy.
+		keepArtifactBool = true
+	}
+
+	return &Artifact{
+		Copies: copied,
+		StateData: map[string]interface{}{
+			"generated_data": artifact.State("generated_data"),
+		},
+	}, keepArtifactBool, false, nil
+}
+
+// regionConfig returns the AWS config for the given region. The role is
+// assumed if set, otherwise the current credentials are used.
+func (p *PostProcessor) regionConfig(awscfg *aws.Config, role, region string) aws.Config {
+	cfg := awscfg.Copy()
+	cfg.Region = region
+	if role != "" {
+		stsc := sts.NewFromConfig(awscfg.Copy())
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsc, role))
+	}
+	return cfg
+}
+
+// provenance returns the provenance of the copies, if provenance tags are
+// enabled.
+func (p *PostProcessor) provenance() *amicopy.Provenance {
+	if !p.config.AddProvenanceTags {
+		return nil
+	}
+	return &amicopy.Provenance{
+		Prefix:        p.config.ProvenanceTagPrefix,
+		BuildUUID:     os.Getenv("PACKER_RUN_UUID"),
+		PluginVersion: Version,
 	}
+}
+
+// stringPointers returns pointers to each of the strings, to update them in
+// place.
+func stringPointers(s []string) []*string {
+	pointers := make([]*string, len(s))
+	for i := range s {
+		pointers[i] = &s[i]
+	}
+	return pointers
+}
 
-	return artifact, keepArtifactBool, false, nil
+// renderTags interpolates the values of the tags for the current copy.
+func (p *PostProcessor) renderTags(m map[string]string) (map[string]string, error) {
+	tags := make(map[string]string, len(m))
+	for k, v := range m {
+		value, err := interpolate.Render(v, &p.config.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", k, err)
+		}
+		tags[k] = value
+	}
+	return tags, nil
 }
 
-func copyAMIs(ctx context.Context, copies []amicopy.AmiCopy, ui packer.Ui, manifestOutput string, concurrencyCount int) int32 {
+// ssmTags converts a map of tags to SSM tags, sorted by key.
+func ssmTags(m map[string]string) (tags []ssmtypes.Tag) {
+	for _, tag := range amicopy.TagsFromMap(m) {
+		tags = append(tags, ssmtypes.Tag{Key: tag.Key, Value: tag.Value})
+	}
+	return tags
+}
+
+// copyAMIs executes the copies, returning those that succeeded and a count of
+// those that failed. Copies are left pending and counted as failed once the
+// context is cancelled. Copies `resumed` from a previous run are only
+// completed, as they have already been submitted.
+//
+// Up to `concurrencyCount` copies are submitted at a time, and then completed
+// separately once available so that slow copies do not hold up submitting the
+// rest. Copies hold their slot in the per account and region `limits` until
+// they complete, and are re-queued with backoff when they hit AWS quotas or
+// throttling.
+func copyAMIs(ctx context.Context, copies, resumed []amicopy.AmiCopy, ui packer.Ui, concurrencyCount int,
+	limits *copyLimits) ([]amicopy.AmiCopy, int32) {
+
 	// Copy execution loop
 	var (
-		copyCount    = len(copies)
-		copyTasks    = make(chan amicopy.AmiCopy, copyCount)
-		amiManifests = make(chan *amicopy.AmiManifest, copyCount)
-		copyErrs     int32
-		wg           sync.WaitGroup
+		copyCount = len(copies) + len(resumed)
+		copied    = make(chan amicopy.AmiCopy, copyCount)
+		copyErrs  int32
+		wg        sync.WaitGroup
 	)
 	var workers int
 	{
@@ -220,79 +816,252 @@ func copyAMIs(ctx context.Context, copies []amicopy.AmiCopy, ui packer.Ui, manif
 			workers = copyCount
 		}
 	}
-	for i := 0; i < workers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for c := range copyTasks {
-				input := c.Input()
-				ui.Say(
-					fmt.Sprintf(
-						"[%s] Copying %s to account %s (encrypted: %t)",
-						*input.SourceRegion,
-						*input.SourceImageId,
-						c.TargetAccountID(),
-						*input.Encrypted,
-					),
-				)
-				if err := c.Copy(ctx, &ui); err != nil {
-					ui.Say(err.Error())
-					atomic.AddInt32(&copyErrs, 1)
-					continue
+	submitSlots := make(chan struct{}, workers)
+
+	completeAMI := func(c amicopy.AmiCopy) error {
+		release, err := limits.acquire(ctx, c.TargetAccountID(), c.TargetRegion())
+		if err != nil {
+			return err
+		}
+		defer release()
+		return c.Complete(ctx, &ui)
+	}
+
+	copyAMI := func(c amicopy.AmiCopy) error {
+		input := c.Input()
+		for attempt := 1; ; attempt++ {
+			release, err := limits.acquire(ctx, c.TargetAccountID(), c.TargetRegion())
+			if err != nil {
+				return err
+			}
+			select {
+			case submitSlots <- struct{}{}:
+			case <-ctx.Done():
+				release()
+				return ctx.Err()
+			}
+
+			ui.Say(
+				fmt.Sprintf(
+					"[%s] Copying %s to account %s in %s (encrypted: %t)",
+					*input.SourceRegion,
+					*input.SourceImageId,
+					c.TargetAccountID(),
+					c.TargetRegion(),
+					*input.Encrypted,
+				),
+			)
+			err = c.Submit(ctx, &ui)
+			<-submitSlots
+
+			if err != nil {
+				release()
+				if !amicopy.IsLimitError(err) || attempt == maxCopyAttempts {
+					return err
 				}
-				output := c.Output()
-				manifest := &amicopy.AmiManifest{
-					AccountID: c.TargetAccountID(),
-					Region:    *input.SourceRegion,
-					ImageID:   *output.ImageId,
+
+				delay := requeueBackoff(attempt)
+				ui.Say(fmt.Sprintf("[%s] Re-queueing copy of %s to account %s in %s in %s: %s",
+					*input.SourceRegion, *input.SourceImageId, c.TargetAccountID(), c.TargetRegion(), delay, err))
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return ctx.Err()
 				}
-				amiManifests <- manifest
-
-				ui.Say(
-					fmt.Sprintf(
-						"[%s] Finished copying %s to %s (copied id: %s)",
-						*input.SourceRegion,
-						*input.SourceImageId,
-						c.TargetAccountID(),
-						*output.ImageId,
-					),
-				)
+				continue
 			}
-		}()
+
+			err = c.Complete(ctx, &ui)
+			release()
+			return err
+		}
 	}
 
-	// Copy task submission
-	for _, copy := range copies {
-		copyTasks <- copy
+	for i, c := range append(resumed, copies...) {
+		run := copyAMI
+		if i < len(resumed) {
+			run = completeAMI
+		}
+
+		wg.Add(1)
+		go func(c amicopy.AmiCopy, run func(amicopy.AmiCopy) error) {
+			defer wg.Done()
+			if ctx.Err() != nil {
+				atomic.AddInt32(&copyErrs, 1)
+				return
+			}
+
+			if err := run(c); err != nil {
+				ui.Say(err.Error())
+				atomic.AddInt32(&copyErrs, 1)
+				return
+			}
+			copied <- c
+
+			input := c.Input()
+			ui.Say(
+				fmt.Sprintf(
+					"[%s] Finished copying %s to %s in %s (copied id: %s)",
+					*input.SourceRegion,
+					*input.SourceImageId,
+					c.TargetAccountID(),
+					c.TargetRegion(),
+					*c.Output().ImageId,
+				),
+			)
+		}(c, run)
 	}
-	close(copyTasks)
 	wg.Wait()
+	close(copied)
 
-	if manifestOutput != "" {
-		manifests := []*amicopy.AmiManifest{}
-	LOOP:
-		for {
-			select {
-			case m := <-amiManifests:
-				manifests = append(manifests, m)
-			default:
-				break LOOP
+	var results []amicopy.AmiCopy
+	for c := range copied {
+		results = append(results, c)
+	}
+	return results, copyErrs
+}
+
+// finalizeAMIs publishes the copies, and prunes or deprecates older images in
+// their lineage, once every copy has succeeded, returning a count of those that
+// failed. Older images are pruned or deprecated once per account, region and
+// lineage, however many copies are in it.
+func finalizeAMIs(ctx context.Context, copies []amicopy.AmiCopy, ui packer.Ui) int32 {
+	var (
+		failed      = make([]int32, len(copies))
+		lineages    = map[string][]int{}
+		runImageIDs []string
+		wg          sync.WaitGroup
+	)
+	for i, c := range copies {
+		runImageIDs = append(runImageIDs,
+			aws.ToString(c.Input().SourceImageId), aws.ToString(c.Output().ImageId))
+		if key := c.LineageKey(); key != "" {
+			lineages[key] = append(lineages[key], i)
+		}
+	}
+
+	for i, c := range copies {
+		wg.Add(1)
+		go func(i int, c amicopy.AmiCopy) {
+			defer wg.Done()
+			if err := c.Finalize(ctx, &ui); err != nil {
+				ui.Say(err.Error())
+				atomic.StoreInt32(&failed[i], 1)
 			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, lineage := range lineages {
+		wg.Add(1)
+		go func(lineage []int) {
+			defer wg.Done()
+			if err := copies[lineage[0]].ManageLineage(ctx, &ui, runImageIDs, len(lineage)); err != nil {
+				ui.Say(err.Error())
+				for _, i := range lineage {
+					atomic.StoreInt32(&failed[i], 1)
+				}
+			}
+		}(lineage)
+	}
+	wg.Wait()
+
+	var finalizeErrs int32
+	for _, f := range failed {
+		finalizeErrs += f
+	}
+	return finalizeErrs
+}
+
+// requeueBackoff returns how long to wait before re-queueing a copy that hit
+// AWS quotas or throttling for the given time.
+func requeueBackoff(attempt int) time.Duration {
+	delay := 15 * time.Second << (attempt - 1)
+	if delay > 5*time.Minute {
+		delay = 5 * time.Minute
+	}
+	return delay
+}
+
+// resumeAMIs splits the copies into those still pending and those with an
+// image recorded in the manifest of a previous run that still exists in the
+// target. Failed copies are always retried, relying on `skip_existing` to pick
+// up any image they left behind. Resumed copies may still be pending, and must
+// still be completed.
+func resumeAMIs(ctx context.Context, copies []amicopy.AmiCopy, ui packer.Ui, manifestPath string) (
+	pending, resumed []amicopy.AmiCopy, err error) {
+
+	manifests, err := readManifests(manifestPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to read manifest %s to resume from: %s", manifestPath, err)
+	}
+
+	previous := make(map[string]string, len(manifests))
+	for _, m := range manifests {
+		switch m.Status {
+		case amicopy.ManifestStatusFailed, amicopy.ManifestStatusDeregistered:
+			continue
 		}
-		err := writeManifests(manifestOutput, manifests)
-		if err != nil {
-			ui.Say(fmt.Sprintf("Unable to write out manifest to %s: %s", manifestOutput, err))
+		if m.ImageID != "" {
+			previous[m.Key()] = m.ImageID
+		}
+	}
+
+	for _, c := range copies {
+		imageID, ok := previous[c.Manifest().Key()]
+		if ok {
+			if ok, err = c.Resume(ctx, imageID); err != nil {
+				return nil, nil, err
+			}
 		}
+		if !ok {
+			pending = append(pending, c)
+			continue
+		}
+		ui.Say(fmt.Sprintf("[%s] Resuming with existing copy %s of %s in account %s",
+			c.TargetRegion(), imageID, *c.Input().SourceImageId, c.TargetAccountID()))
+		resumed = append(resumed, c)
 	}
-	close(amiManifests)
+	return pending, resumed, nil
+}
 
-	return copyErrs
+// rollbackAMIs deregisters the given copies and deletes their snapshots,
+// returning those that could not be removed. Only copies created by this run
+// are removed, existing and resumed copies are left in place. The copies are
+// rolled back even if the context is cancelled, as an interrupted run is when
+// they are most likely to be left behind.
+func rollbackAMIs(ctx context.Context, copies []amicopy.AmiCopy, ui packer.Ui) (remaining []amicopy.AmiCopy) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	for _, c := range copies {
+		if !c.Created() {
+			continue
+		}
+		imageID := *c.Output().ImageId
+		if err := c.Deregister(ctx); err != nil {
+			ui.Say(fmt.Sprintf("[%s] Unable to roll back copy %s in account %s: %s",
+				c.TargetRegion(), imageID, c.TargetAccountID(), err))
+			remaining = append(remaining, c)
+			continue
+		}
+		ui.Say(fmt.Sprintf("[%s] Rolled back copy %s in account %s",
+			c.TargetRegion(), imageID, c.TargetAccountID()))
+	}
+	return remaining
+}
+
+// regionClients are the AWS clients for a target account and region.
+type regionClients struct {
+	ec2 *ec2.Client
+	ssm *ssm.Client
 }
 
 // ami encapsulates simplistic details about an AMI.
 type ami struct {
 	id     string
 	region string
+	conn   *ec2.Client
 }
 
 // amisFromArtifactID returns an AMI slice from a Packer artifact id.
@@ -303,11 +1072,3 @@ func amisFromArtifactID(artifactID string) (amis []*ami) {
 	}
 	return amis
 }
-
-func writeManifests(output string, manifests []*amicopy.AmiManifest) error {
-	rawManifest, err := json.Marshal(manifests)
-	if err != nil {
-		return err
-	}
-	return ioutil.WriteFile(output, rawManifest, 0644)
-}
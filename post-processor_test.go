@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/martinbaillie/packer-plugin-ami-copy/amicopy"
+)
+
+// fakeCopy is a copy whose previous images are `existing`, recording how it
+// is driven.
+type fakeCopy struct {
+	amicopy.AmiCopy
+
+	manifest  *amicopy.AmiManifest
+	existing  map[string]bool
+	output    *ec2.CopyImageOutput
+	lineage   string
+	submitted bool
+	completed bool
+	finalized bool
+	managed   []int
+}
+
+func (c *fakeCopy) Manifest() *amicopy.AmiManifest { return c.manifest }
+func (c *fakeCopy) TargetAccountID() string        { return c.manifest.AccountID }
+func (c *fakeCopy) TargetRegion() string           { return c.manifest.Region }
+func (c *fakeCopy) Output() *ec2.CopyImageOutput   { return c.output }
+
+func (c *fakeCopy) Input() *ec2.CopyImageInput {
+	return &ec2.CopyImageInput{
+		SourceImageId: aws.String(c.manifest.SourceImageID),
+		SourceRegion:  aws.String(c.manifest.SourceRegion),
+		Encrypted:     aws.Bool(false),
+	}
+}
+
+func (c *fakeCopy) Resume(_ context.Context, imageID string) (bool, error) {
+	if !c.existing[imageID] {
+		return false, nil
+	}
+	c.output = &ec2.CopyImageOutput{ImageId: aws.String(imageID)}
+	return true, nil
+}
+
+func (c *fakeCopy) Submit(context.Context, *packer.Ui) error {
+	c.submitted = true
+	c.output = &ec2.CopyImageOutput{ImageId: aws.String("ami-new")}
+	return nil
+}
+
+func (c *fakeCopy) Complete(context.Context, *packer.Ui) error {
+	c.completed = true
+	return nil
+}
+
+func (c *fakeCopy) Finalize(context.Context, *packer.Ui) error {
+	c.finalized = true
+	return nil
+}
+
+func (c *fakeCopy) LineageKey() string { return c.lineage }
+
+func (c *fakeCopy) ManageLineage(_ context.Context, _ *packer.Ui, _ []string, copies int) error {
+	c.managed = append(c.managed, copies)
+	return nil
+}
+
+func newFakeCopy(region string, existing ...string) *fakeCopy {
+	c := &fakeCopy{
+		manifest: &amicopy.AmiManifest{
+			AccountID:     "123456789012",
+			Region:        region,
+			SourceImageID: "ami-source",
+			SourceRegion:  "us-east-1",
+		},
+		existing: map[string]bool{},
+	}
+	for _, imageID := range existing {
+		c.existing[imageID] = true
+	}
+	return c
+}
+
+func TestResumeAMIs(t *testing.T) {
+	var (
+		resumable = newFakeCopy("eu-west-1", "ami-west")
+		failed    = newFakeCopy("eu-central-1", "ami-central")
+		gone      = newFakeCopy("ap-southeast-2")
+		unknown   = newFakeCopy("us-west-2", "ami-oregon")
+	)
+
+	previous := amicopy.NewManifest([]*amicopy.AmiManifest{
+		{AccountID: "123456789012", Region: "eu-west-1", SourceImageID: "ami-source", SourceRegion: "us-east-1",
+			ImageID: "ami-west", Status: amicopy.ManifestStatusCopied},
+		{AccountID: "123456789012", Region: "eu-central-1", SourceImageID: "ami-source", SourceRegion: "us-east-1",
+			ImageID: "ami-central", Status: amicopy.ManifestStatusFailed},
+		{AccountID: "123456789012", Region: "ap-southeast-2", SourceImageID: "ami-source", SourceRegion: "us-east-1",
+			ImageID: "ami-sydney", Status: amicopy.ManifestStatusCopied},
+	})
+	data, err := previous.Encode(amicopy.ManifestFormatJSON, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	if err = os.WriteFile(manifestPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, resumed, err := resumeAMIs(context.Background(),
+		[]amicopy.AmiCopy{resumable, failed, gone, unknown}, packer.TestUi(t), manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resumed) != 1 || resumed[0] != resumable {
+		t.Errorf("expected only the copy with an existing image to be resumed, got %d", len(resumed))
+	}
+	if len(pending) != 3 {
+		t.Errorf("expected 3 pending copies, got %d", len(pending))
+	}
+
+	// Resumed copies may still be pending, so are completed but not copied
+	// again.
+	copied, copyErrs := copyAMIs(context.Background(), pending, resumed, packer.TestUi(t), 0, &copyLimits{})
+	if copyErrs != 0 || len(copied) != 4 {
+		t.Fatalf("expected 4 copies to succeed, got %d with %d errors", len(copied), copyErrs)
+	}
+	if resumable.submitted || !resumable.completed {
+		t.Errorf("expected the resumed copy to only be completed")
+	}
+	for _, c := range []*fakeCopy{failed, gone, unknown} {
+		if !c.submitted || !c.completed {
+			t.Errorf("expected the copy to %s to be submitted and completed", c.manifest.Region)
+		}
+	}
+}
+
+func TestPostProcessor_Configure_perCopyTags(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"tag": []map[string]interface{}{
+			{"key": "Region", "value": "{{ .Region }}"},
+		},
+		"snapshot_tag": []map[string]interface{}{
+			{"key": "Source", "value": "{{ .SourceAMI }}"},
+		},
+		"target": []map[string]interface{}{
+			{"account_id": "123456789012", "tags": map[string]string{"Account": "{{ .TargetAccount }}"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tags are only rendered per copy, once the copy's data is known.
+	for _, tc := range []struct{ actual, expected string }{
+		{p.config.AMITags["Region"], "{{ .Region }}"},
+		{p.config.SnapshotTags["Source"], "{{ .SourceAMI }}"},
+		{p.config.Targets[0].Tags["Account"], "{{ .TargetAccount }}"},
+		{p.config.Targets[0].AccountID, "123456789012"},
+	} {
+		if tc.actual != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, tc.actual)
+		}
+	}
+}
+
+func TestFinalizeAMIs(t *testing.T) {
+	var (
+		west    = newFakeCopy("eu-west-1")
+		central = newFakeCopy("eu-central-1")
+		other   = newFakeCopy("eu-west-2")
+		none    = newFakeCopy("us-west-2")
+	)
+	west.lineage, central.lineage, other.lineage = "base", "base", "other"
+
+	var copies []amicopy.AmiCopy
+	for _, c := range []*fakeCopy{west, central, other, none} {
+		c.output = &ec2.CopyImageOutput{ImageId: aws.String("ami-" + c.manifest.Region)}
+		copies = append(copies, c)
+	}
+
+	if finalizeErrs := finalizeAMIs(context.Background(), copies, packer.TestUi(t)); finalizeErrs != 0 {
+		t.Fatalf("expected no errors, got %d", finalizeErrs)
+	}
+	for _, c := range []*fakeCopy{west, central, other, none} {
+		if !c.finalized {
+			t.Errorf("expected the copy to %s to be finalized", c.manifest.Region)
+		}
+	}
+
+	// Each lineage is managed once, counting all of its copies.
+	managed := append(append(west.managed, central.managed...), other.managed...)
+	if len(managed) != 2 || len(none.managed) != 0 {
+		t.Fatalf("expected 2 lineages to be managed once each, got %v", managed)
+	}
+	if len(west.managed)+len(central.managed) != 1 || append(west.managed, central.managed...)[0] != 2 {
+		t.Errorf("expected the shared lineage to be managed once for 2 copies")
+	}
+	if len(other.managed) != 1 || other.managed[0] != 1 {
+		t.Errorf("expected the other lineage to be managed once for 1 copy")
+	}
+}